@@ -0,0 +1,216 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Authenticator knows how to attach credentials to an outgoing GitHub API
+// request. apiCall defers to one instead of hardcoding a single auth scheme,
+// so the bot can run as either a personal account or a GitHub App.
+type Authenticator interface {
+	Authorize(req *http.Request) error
+}
+
+// BasicAuthAuthenticator is the original personal-access-token auth scheme.
+type BasicAuthAuthenticator struct {
+	UserName string
+	Token    string
+}
+
+func (a *BasicAuthAuthenticator) Authorize(req *http.Request) error {
+	basicAuthToken := base64.StdEncoding.EncodeToString([]byte(a.UserName + ":" + a.Token))
+	req.Header.Add("Authorization", "Basic "+basicAuthToken)
+	return nil
+}
+
+// GitHubAppAuthenticator authenticates as a GitHub App installation. It
+// signs a short-lived JWT with the app's private key, exchanges it for an
+// installation access token, and caches that token until shortly before it
+// expires so most requests don't pay the exchange round trip.
+type GitHubAppAuthenticator struct {
+	AppID          string
+	InstallationID string
+	PrivateKeyPath string
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+
+	// httpClient is overridden in tests to stub the token-exchange call;
+	// nil means http.DefaultClient.
+	httpClient *http.Client
+}
+
+func (a *GitHubAppAuthenticator) client() *http.Client {
+	if a.httpClient != nil {
+		return a.httpClient
+	}
+	return http.DefaultClient
+}
+
+func NewGitHubAppAuthenticator(appID string, installationID string, privateKeyPath string) *GitHubAppAuthenticator {
+	return &GitHubAppAuthenticator{
+		AppID:          appID,
+		InstallationID: installationID,
+		PrivateKeyPath: privateKeyPath,
+	}
+}
+
+func (a *GitHubAppAuthenticator) Authorize(req *http.Request) error {
+	token, err := a.installationToken()
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Authorization", "token "+token)
+	return nil
+}
+
+// installationToken returns a cached installation token, refreshing it if
+// it's missing or within 5 minutes of expiry.
+func (a *GitHubAppAuthenticator) installationToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Now().Before(a.tokenExpiry.Add(-5*time.Minute)) {
+		return a.token, nil
+	}
+
+	jwtToken, err := a.signAppJWT()
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%s/access_tokens", gitHubApiBaseUrl, a.InstallationID)
+	req, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Add("Authorization", "Bearer "+jwtToken)
+	req.Header.Add("content-type", "application/json")
+
+	res, err := a.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if res.StatusCode >= 300 {
+		return "", fmt.Errorf("failed to exchange app JWT for installation token: %d %s", res.StatusCode, body)
+	}
+
+	var tokenResponse struct {
+		Token     string `json:"token"`
+		ExpiresAt string `json:"expires_at"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", err
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, tokenResponse.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	a.token = tokenResponse.Token
+	a.tokenExpiry = expiresAt
+	return a.token, nil
+}
+
+// signAppJWT builds and signs a short-lived RS256 JWT as described in
+// GitHub's app authentication docs: header.claims.signature, each segment
+// base64url-encoded without padding.
+func (a *GitHubAppAuthenticator) signAppJWT() (string, error) {
+	key, err := a.loadPrivateKey()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	claims := map[string]interface{}{
+		"iat": now.Add(-30 * time.Second).Unix(),
+		"exp": now.Add(10 * time.Minute).Unix(),
+		"iss": a.AppID,
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64URLEncode(headerJSON) + "." + base64URLEncode(claimsJSON)
+
+	hashed := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (a *GitHubAppAuthenticator) loadPrivateKey() (*rsa.PrivateKey, error) {
+	pemBytes, err := ioutil.ReadFile(a.PrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", a.PrivateKeyPath)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key in %s is not an RSA key", a.PrivateKeyPath)
+	}
+	return key, nil
+}
+
+func base64URLEncode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+var appAuthenticator *GitHubAppAuthenticator
+var appAuthenticatorOnce sync.Once
+
+// resolveAuthenticator picks the Authenticator implied by settings, caching
+// the GitHub App authenticator across calls so its token cache is shared.
+func resolveAuthenticator(settings Settings) Authenticator {
+	if settings.AuthMode == "app" {
+		appAuthenticatorOnce.Do(func() {
+			appAuthenticator = NewGitHubAppAuthenticator(settings.GitHubAppID, settings.GitHubInstallationID, settings.GitHubPrivateKeyPath)
+		})
+		return appAuthenticator
+	}
+	return &BasicAuthAuthenticator{UserName: settings.GitHubUserName, Token: settings.GitHubToken}
+}
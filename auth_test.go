@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBasicAuthAuthenticator(t *testing.T) {
+	auth := &BasicAuthAuthenticator{UserName: "JimmyD", Token: "secret"}
+	req, err := http.NewRequest("GET", "https://api.github.com", nil)
+	if err != nil {
+		t.Fatalf("could not create request: %v", err)
+	}
+
+	if err := auth.Authorize(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Basic ") {
+		t.Fatalf("expected a Basic auth header, got: %s", header)
+	}
+}
+
+func writeTestPrivateKey(t *testing.T) string {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate rsa key: %v", err)
+	}
+
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	f, err := os.CreateTemp("", "app-key-*.pem")
+	if err != nil {
+		t.Fatalf("could not create temp file: %v", err)
+	}
+	defer f.Close()
+
+	if err := pem.Encode(f, block); err != nil {
+		t.Fatalf("could not write pem: %v", err)
+	}
+	return f.Name()
+}
+
+func TestGitHubAppAuthenticatorSignsJWT(t *testing.T) {
+	keyPath := writeTestPrivateKey(t)
+	defer os.Remove(keyPath)
+
+	auth := NewGitHubAppAuthenticator("12345", "67890", keyPath)
+
+	token, err := auth.signAppJWT()
+	if err != nil {
+		t.Fatalf("unexpected error signing jwt: %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a 3 segment JWT, got %d segments", len(parts))
+	}
+}
+
+func TestGitHubAppAuthenticatorMissingKey(t *testing.T) {
+	auth := NewGitHubAppAuthenticator("12345", "67890", "/no/such/file.pem")
+
+	if _, err := auth.signAppJWT(); err == nil {
+		t.Fatalf("expected an error reading a missing private key")
+	}
+}
+
+// stubRoundTripper returns a fixed response for every request, tracking how
+// many times it was called so tests can assert on cache reuse.
+type stubRoundTripper struct {
+	calls    int
+	response string
+}
+
+func (s *stubRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.calls++
+	return &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(s.response)),
+		Header:     make(http.Header),
+	}, nil
+}
+
+func TestGitHubAppAuthenticatorInstallationTokenCachesUntilExpiry(t *testing.T) {
+	keyPath := writeTestPrivateKey(t)
+	defer os.Remove(keyPath)
+
+	stub := &stubRoundTripper{response: `{"token": "first-token", "expires_at": "` + time.Now().Add(1*time.Hour).Format(time.RFC3339) + `"}`}
+	auth := NewGitHubAppAuthenticator("12345", "67890", keyPath)
+	auth.httpClient = &http.Client{Transport: stub}
+
+	token, err := auth.installationToken()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "first-token" {
+		t.Fatalf("expected first-token, got %s", token)
+	}
+	if stub.calls != 1 {
+		t.Fatalf("expected 1 token exchange call, got %d", stub.calls)
+	}
+
+	// well within the cached token's lifetime: no new exchange should happen
+	token, err = auth.installationToken()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "first-token" {
+		t.Fatalf("expected cached first-token, got %s", token)
+	}
+	if stub.calls != 1 {
+		t.Fatalf("expected cached token to be reused, but exchange was called %d times", stub.calls)
+	}
+}
+
+func TestGitHubAppAuthenticatorInstallationTokenRefreshesNearExpiry(t *testing.T) {
+	keyPath := writeTestPrivateKey(t)
+	defer os.Remove(keyPath)
+
+	stub := &stubRoundTripper{response: `{"token": "stale-token", "expires_at": "` + time.Now().Add(1*time.Minute).Format(time.RFC3339) + `"}`}
+	auth := NewGitHubAppAuthenticator("12345", "67890", keyPath)
+	auth.httpClient = &http.Client{Transport: stub}
+
+	token, err := auth.installationToken()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "stale-token" {
+		t.Fatalf("expected stale-token, got %s", token)
+	}
+
+	// the cached token expires in 1 minute, inside the 5 minute refresh
+	// cutoff, so this call must re-exchange rather than reuse it
+	stub.response = `{"token": "fresh-token", "expires_at": "` + time.Now().Add(1*time.Hour).Format(time.RFC3339) + `"}`
+	token, err = auth.installationToken()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "fresh-token" {
+		t.Fatalf("expected a refreshed token, got %s", token)
+	}
+	if stub.calls != 2 {
+		t.Fatalf("expected 2 token exchange calls, got %d", stub.calls)
+	}
+}
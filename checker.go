@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Typed errors returned by the PullChecker pipeline. autoMerge maps these
+// back to the user-visible comments it has always posted, so the wording
+// intentionally matches what was hardcoded there before.
+var (
+	ErrNotOpen             = fmt.Errorf("pull request is not open")
+	ErrNotMergeable        = fmt.Errorf("pull request is not mergeable")
+	ErrNotClean            = fmt.Errorf("pull request mergeable state is not clean")
+	ErrDraft               = fmt.Errorf("pull request is marked as work in progress")
+	ErrAwaitingReview      = fmt.Errorf("pull request does not have enough approving reviews")
+	ErrChecksFailing       = fmt.Errorf("required status checks have not passed")
+	ErrChangesRequested    = fmt.Errorf("pull request has changes requested")
+	ErrBlockedByDependency = fmt.Errorf("pull request depends on an open issue")
+)
+
+var wipTitleMarker = regexp.MustCompile(`(?i)\b(wip|draft)\b`)
+var dependsOnMarker = regexp.MustCompile(`(?i)depends on #(\d+)`)
+
+type Review struct {
+	User  User   `json:"user"`
+	State string `json:"state"`
+}
+
+type CheckRun struct {
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+}
+
+type CheckRunsResponse struct {
+	CheckRuns []CheckRun `json:"check_runs"`
+}
+
+type CombinedStatus struct {
+	State      string `json:"state"`
+	TotalCount int    `json:"total_count"`
+}
+
+// mergeabilityCheck is a single predicate in the PullChecker pipeline. It
+// returns nil when the predicate is satisfied, or a typed error otherwise.
+type mergeabilityCheck func(pr PullRequest, event IssueCommentWebhookEvent, settings Settings) error
+
+// PullChecker decides whether a pull request is actually mergeable by
+// running an ordered pipeline of predicates, some of which hit the GitHub
+// API directly rather than trusting the `mergeable` bool alone.
+//
+// There is deliberately no "unresolved review threads" predicate. An
+// earlier attempt (49eb48c) tried to infer thread resolution from the REST
+// `/pulls/{n}/comments` endpoint's `position` field, but `position` just
+// marks where in the diff a comment anchors, not whether its thread was
+// resolved, and the check was reverted (2439c69). Resolution status is only
+// exposed via the GraphQL `reviewThreads.isResolved` field, which this
+// REST-only pipeline doesn't call; adding it back would mean introducing a
+// GraphQL client for this one predicate. Out of scope here, not an
+// oversight.
+type PullChecker struct {
+	apiCall ApiCall
+	checks  []mergeabilityCheck
+}
+
+func NewPullChecker(apiCall ApiCall) *PullChecker {
+	pc := &PullChecker{apiCall: apiCall}
+	pc.checks = []mergeabilityCheck{
+		pc.checkOpen,
+		pc.checkMergeable,
+		pc.checkCleanState,
+		pc.checkNotDraft,
+		pc.checkChangesRequested,
+		pc.checkApprovals,
+		pc.checkStatusChecks,
+		pc.checkDependencies,
+	}
+	return pc
+}
+
+// CheckMergeability runs the pipeline in order and returns the first
+// predicate's error, or nil if the pull request may be merged.
+func (pc *PullChecker) CheckMergeability(pr PullRequest, event IssueCommentWebhookEvent, settings Settings) error {
+	for _, check := range pc.checks {
+		if err := check(pr, event, settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pc *PullChecker) checkOpen(pr PullRequest, event IssueCommentWebhookEvent, settings Settings) error {
+	if event.Issue.State != "open" {
+		return ErrNotOpen
+	}
+	return nil
+}
+
+func (pc *PullChecker) checkMergeable(pr PullRequest, event IssueCommentWebhookEvent, settings Settings) error {
+	if !pr.Mergeable {
+		return ErrNotMergeable
+	}
+	return nil
+}
+
+func (pc *PullChecker) checkCleanState(pr PullRequest, event IssueCommentWebhookEvent, settings Settings) error {
+	if pr.MergeableState != "" && pr.MergeableState != "clean" {
+		return ErrNotClean
+	}
+	return nil
+}
+
+func (pc *PullChecker) checkNotDraft(pr PullRequest, event IssueCommentWebhookEvent, settings Settings) error {
+	if pr.Draft || wipTitleMarker.MatchString(pr.Title) {
+		return ErrDraft
+	}
+	return nil
+}
+
+func (pc *PullChecker) fetchReviews(event IssueCommentWebhookEvent, settings Settings) ([]Review, error) {
+	url := fmt.Sprintf("%s/repos/%s/pulls/%d/reviews", gitHubApiBaseUrl, event.Repository.FullName, event.Issue.Number)
+	res := pc.apiCall(url, "GET", "", settings)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	var reviews []Review
+	if err := json.Unmarshal(res.Body, &reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// latestReviewPerUser keeps only the most recent review state per reviewer,
+// since GitHub returns every review event, not just the current state.
+func latestReviewPerUser(reviews []Review) map[string]string {
+	latest := make(map[string]string)
+	for _, r := range reviews {
+		latest[r.User.Login] = r.State
+	}
+	return latest
+}
+
+func (pc *PullChecker) checkApprovals(pr PullRequest, event IssueCommentWebhookEvent, settings Settings) error {
+	required := 1
+	if settings.RequiredApprovals != "" {
+		if parsed, err := strconv.Atoi(settings.RequiredApprovals); err == nil {
+			required = parsed
+		}
+	}
+
+	reviews, err := pc.fetchReviews(event, settings)
+	if err != nil {
+		return err
+	}
+
+	approvals := 0
+	for _, state := range latestReviewPerUser(reviews) {
+		if state == "APPROVED" {
+			approvals++
+		}
+	}
+
+	if approvals < required {
+		return ErrAwaitingReview
+	}
+	return nil
+}
+
+// Approvers returns the logins whose most recent review is an approval, for
+// use in commit message templates.
+func (pc *PullChecker) Approvers(event IssueCommentWebhookEvent, settings Settings) ([]string, error) {
+	reviews, err := pc.fetchReviews(event, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var approvers []string
+	for login, state := range latestReviewPerUser(reviews) {
+		if state == "APPROVED" {
+			approvers = append(approvers, login)
+		}
+	}
+	return approvers, nil
+}
+
+func (pc *PullChecker) checkChangesRequested(pr PullRequest, event IssueCommentWebhookEvent, settings Settings) error {
+	reviews, err := pc.fetchReviews(event, settings)
+	if err != nil {
+		return err
+	}
+
+	for _, state := range latestReviewPerUser(reviews) {
+		if state == "CHANGES_REQUESTED" {
+			return ErrChangesRequested
+		}
+	}
+	return nil
+}
+
+func (pc *PullChecker) checkStatusChecks(pr PullRequest, event IssueCommentWebhookEvent, settings Settings) error {
+	checkRunsUrl := fmt.Sprintf("%s/repos/%s/commits/%s/check-runs", gitHubApiBaseUrl, event.Repository.FullName, pr.Head.Sha)
+	checkRunsRes := pc.apiCall(checkRunsUrl, "GET", "", settings)
+	if checkRunsRes.Error != nil {
+		return checkRunsRes.Error
+	}
+
+	var checkRuns CheckRunsResponse
+	if err := json.Unmarshal(checkRunsRes.Body, &checkRuns); err != nil {
+		return err
+	}
+
+	for _, run := range checkRuns.CheckRuns {
+		if run.Status != "completed" {
+			return ErrChecksFailing
+		}
+		if run.Conclusion != "success" && run.Conclusion != "neutral" && run.Conclusion != "skipped" {
+			return ErrChecksFailing
+		}
+	}
+
+	statusUrl := fmt.Sprintf("%s/repos/%s/commits/%s/status", gitHubApiBaseUrl, event.Repository.FullName, pr.Head.Sha)
+	statusRes := pc.apiCall(statusUrl, "GET", "", settings)
+	if statusRes.Error != nil {
+		return statusRes.Error
+	}
+
+	var combined CombinedStatus
+	if err := json.Unmarshal(statusRes.Body, &combined); err != nil {
+		return err
+	}
+
+	// TotalCount == 0 means the ref has no legacy commit statuses at all
+	// (the overwhelming majority of repos today, which rely solely on
+	// check-runs above), in which case GitHub reports State "pending"
+	// rather than "success" even though there's nothing to wait on.
+	if combined.TotalCount != 0 && combined.State != "" && combined.State != "success" {
+		return ErrChecksFailing
+	}
+	return nil
+}
+
+func (pc *PullChecker) checkDependencies(pr PullRequest, event IssueCommentWebhookEvent, settings Settings) error {
+	matches := dependsOnMarker.FindAllStringSubmatch(pr.Body, -1)
+	for _, m := range matches {
+		number := strings.TrimSpace(m[1])
+		url := fmt.Sprintf("%s/repos/%s/issues/%s", gitHubApiBaseUrl, event.Repository.FullName, number)
+		res := pc.apiCall(url, "GET", "", settings)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		var issue Issue
+		if err := json.Unmarshal(res.Body, &issue); err != nil {
+			return err
+		}
+
+		if issue.State == "open" {
+			return ErrBlockedByDependency
+		}
+	}
+	return nil
+}
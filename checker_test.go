@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckMergeability(t *testing.T) {
+	openEvent := IssueCommentWebhookEvent{
+		Issue:      Issue{Number: 1, State: "open"},
+		Repository: Repository{FullName: "JohnRoesler/test"},
+	}
+	closedEvent := IssueCommentWebhookEvent{
+		Issue:      Issue{Number: 1, State: "closed"},
+		Repository: Repository{FullName: "JohnRoesler/test"},
+	}
+
+	basePR := PullRequest{Mergeable: true, MergeableState: "clean", Head: Head{Sha: "1234"}}
+
+	approvedReviews := `[{"user":{"login":"SomeReviewer"},"state":"APPROVED"}]`
+	changesRequestedReviews := `[{"user":{"login":"SomeReviewer"},"state":"CHANGES_REQUESTED"}]`
+	noReviews := `[]`
+	passingCheckRuns := `{"check_runs":[{"status":"completed","conclusion":"success"}]}`
+	failingCheckRuns := `{"check_runs":[{"status":"completed","conclusion":"failure"}]}`
+	successStatus := `{"state":"success"}`
+
+	type TestCase struct {
+		name        string
+		event       IssueCommentWebhookEvent
+		pr          PullRequest
+		reviews     string
+		checkRuns   string
+		status      string
+		issueState  string
+		expectedErr error
+	}
+
+	testCases := []TestCase{
+		{
+			name:        "Not open",
+			event:       closedEvent,
+			pr:          basePR,
+			expectedErr: ErrNotOpen,
+		},
+		{
+			name:        "Not mergeable",
+			event:       openEvent,
+			pr:          PullRequest{Mergeable: false},
+			expectedErr: ErrNotMergeable,
+		},
+		{
+			name:        "Mergeable state dirty",
+			event:       openEvent,
+			pr:          PullRequest{Mergeable: true, MergeableState: "dirty"},
+			expectedErr: ErrNotClean,
+		},
+		{
+			name:        "Draft PR",
+			event:       openEvent,
+			pr:          PullRequest{Mergeable: true, MergeableState: "clean", Draft: true},
+			expectedErr: ErrDraft,
+		},
+		{
+			name:        "WIP title marker",
+			event:       openEvent,
+			pr:          PullRequest{Mergeable: true, MergeableState: "clean", Title: "[WIP] add feature"},
+			expectedErr: ErrDraft,
+		},
+		{
+			name:        "Awaiting review",
+			event:       openEvent,
+			pr:          basePR,
+			reviews:     noReviews,
+			checkRuns:   passingCheckRuns,
+			status:      successStatus,
+			expectedErr: ErrAwaitingReview,
+		},
+		{
+			name:        "Changes requested blocks merge",
+			event:       openEvent,
+			pr:          basePR,
+			reviews:     changesRequestedReviews,
+			checkRuns:   passingCheckRuns,
+			status:      successStatus,
+			expectedErr: ErrChangesRequested,
+		},
+		{
+			name:        "Failing status checks",
+			event:       openEvent,
+			pr:          basePR,
+			reviews:     approvedReviews,
+			checkRuns:   failingCheckRuns,
+			status:      successStatus,
+			expectedErr: ErrChecksFailing,
+		},
+		{
+			name:        "Blocked by open dependency",
+			event:       openEvent,
+			pr:          PullRequest{Mergeable: true, MergeableState: "clean", Body: "Depends on #42", Head: Head{Sha: "1234"}},
+			reviews:     approvedReviews,
+			checkRuns:   passingCheckRuns,
+			status:      successStatus,
+			issueState:  "open",
+			expectedErr: ErrBlockedByDependency,
+		},
+		{
+			name:        "Everything green",
+			event:       openEvent,
+			pr:          basePR,
+			reviews:     approvedReviews,
+			checkRuns:   passingCheckRuns,
+			status:      successStatus,
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			stub := func(url string, method string, payload string, s Settings) ApiResponse {
+				switch {
+				case strings.HasSuffix(url, "/reviews"):
+					return ApiResponse{Body: []byte(tc.reviews), StatusCode: 200}
+				case strings.HasSuffix(url, "/check-runs"):
+					return ApiResponse{Body: []byte(tc.checkRuns), StatusCode: 200}
+				case strings.HasSuffix(url, "/status"):
+					return ApiResponse{Body: []byte(tc.status), StatusCode: 200}
+				case strings.Contains(url, "/issues/"):
+					return ApiResponse{Body: []byte(`{"state":"` + tc.issueState + `"}`), StatusCode: 200}
+				default:
+					return ApiResponse{Body: []byte(`{}`), StatusCode: 200}
+				}
+			}
+
+			checker := NewPullChecker(stub)
+			err := checker.CheckMergeability(tc.pr, tc.event, Settings{})
+			if err != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
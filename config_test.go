@@ -0,0 +1,115 @@
+package main
+
+import "testing"
+
+func TestPolicyForFallsBackToBuiltInDefaults(t *testing.T) {
+	policy := PolicyFor(nil, "JohnRoesler/test")
+
+	if policy.MergeMethod != "squash" {
+		t.Errorf("expected default merge method squash, got %s", policy.MergeMethod)
+	}
+	if policy.TriggerComment != mergeComment {
+		t.Errorf("expected default trigger comment %q, got %q", mergeComment, policy.TriggerComment)
+	}
+	if policy.RequiredApprovals != 1 {
+		t.Errorf("expected default required approvals 1, got %d", policy.RequiredApprovals)
+	}
+}
+
+func TestPolicyForLayersConfigDefaultsAndRepoOverrides(t *testing.T) {
+	restrictFalse := false
+	cfg := &Config{
+		Defaults: RepoPolicy{
+			MergeMethod:       "merge",
+			RequiredApprovals: 2,
+			TriggerComment:    "lgtm merge",
+		},
+		Repos: map[string]RepoPolicy{
+			"JohnRoesler/special": {
+				MergeMethod:            "rebase",
+				RestrictMergeRequester: &restrictFalse,
+			},
+		},
+	}
+
+	t.Run("repo without an override uses config defaults", func(t *testing.T) {
+		policy := PolicyFor(cfg, "JohnRoesler/test")
+		if policy.MergeMethod != "merge" {
+			t.Errorf("expected merge method merge, got %s", policy.MergeMethod)
+		}
+		if policy.RequiredApprovals != 2 {
+			t.Errorf("expected required approvals 2, got %d", policy.RequiredApprovals)
+		}
+	})
+
+	t.Run("repo with an override wins, unset fields still fall back", func(t *testing.T) {
+		policy := PolicyFor(cfg, "JohnRoesler/special")
+		if policy.MergeMethod != "rebase" {
+			t.Errorf("expected merge method rebase, got %s", policy.MergeMethod)
+		}
+		if policy.RequiredApprovals != 2 {
+			t.Errorf("expected required approvals to fall back to 2, got %d", policy.RequiredApprovals)
+		}
+		if policy.RestrictMergeRequester == nil || *policy.RestrictMergeRequester {
+			t.Errorf("expected restrict merge requester override to be false")
+		}
+	})
+}
+
+func TestRenderTemplate(t *testing.T) {
+	data := CommitTemplateData{
+		PR:        PullRequest{Title: "Add widget"},
+		Approvers: []string{"alice", "bob"},
+	}
+
+	out, err := renderTemplate("{{.PR.Title}} (approved by {{range .Approvers}}{{.}} {{end}})", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "Add widget (approved by alice bob )"
+	if out != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func TestRenderTemplatePreservesQuotesAndBackslashes(t *testing.T) {
+	// Escaping for JSON is the caller's job (via json.Marshal), not
+	// renderTemplate's, so quotes and backslashes must pass through as-is.
+	data := CommitTemplateData{PR: PullRequest{Title: `Fix "quoted" C:\Users\foo bug`}}
+
+	out, err := renderTemplate("{{.PR.Title}}", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `Fix "quoted" C:\Users\foo bug`
+	if out != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func TestRenderTemplateCollapsesNewlines(t *testing.T) {
+	data := CommitTemplateData{PR: PullRequest{Title: "line one\nline two"}}
+
+	out, err := renderTemplate("{{.PR.Title}}", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "line one line two"
+	if out != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func TestContainsLogin(t *testing.T) {
+	logins := []string{"Alice", "bob"}
+
+	if !containsLogin(logins, "alice") {
+		t.Errorf("expected case-insensitive match for alice")
+	}
+	if containsLogin(logins, "carol") {
+		t.Errorf("expected no match for carol")
+	}
+}
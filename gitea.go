@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GiteaProvider adapts Gitea's webhook format and REST API to the Provider
+// interface. Gitea's issue_comment webhook payload and pull request API
+// shape are modeled closely on GitHub's, so it reuses IssueCommentWebhookEvent
+// and PullRequest directly rather than defining parallel types.
+type GiteaProvider struct{}
+
+func (p *GiteaProvider) Name() string { return "gitea" }
+
+func (p *GiteaProvider) Matches(headers http.Header) bool {
+	return headers.Get("X-Gitea-Event") != "" || strings.Contains(headers.Get("User-Agent"), "Gitea/")
+}
+
+func (p *GiteaProvider) ParseWebhook(headers http.Header, body []byte) (MergeRequestEvent, error) {
+	if err := verifyGiteaSignature(settings.GiteaWebhookSecret, body, headers.Get("X-Gitea-Signature")); err != nil {
+		return MergeRequestEvent{}, fmt.Errorf("%w: %s", ErrInvalidSignature, err)
+	}
+
+	if headers.Get("X-Gitea-Event") != "issue_comment" {
+		return MergeRequestEvent{}, ErrUnsupportedEvent
+	}
+
+	deliveryID := headers.Get("X-Gitea-Delivery")
+	if deliveryID != "" && deliveries.SeenBefore(deliveryID) {
+		return MergeRequestEvent{}, ErrDuplicateDelivery
+	}
+
+	var raw IssueCommentWebhookEvent
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return MergeRequestEvent{}, err
+	}
+
+	return MergeRequestEvent{
+		RepoFullName:  raw.Repository.FullName,
+		Number:        raw.Issue.Number,
+		State:         raw.Issue.State,
+		HTMLURL:       raw.Issue.HTMLurl,
+		CommentBody:   raw.Comment.Body,
+		CommentUser:   raw.Comment.User.Login,
+		IsPullRequest: raw.Issue.EventPullRequest.URL != "",
+		DeliveryID:    deliveryID,
+	}, nil
+}
+
+// verifyGiteaSignature checks the X-Gitea-Signature header, a hex-encoded
+// HMAC-SHA256 of the body with no "sha256=" prefix, unlike GitHub's
+// X-Hub-Signature-256.
+func verifyGiteaSignature(secret string, body []byte, signatureHeader string) error {
+	if secret == "" {
+		return fmt.Errorf("webhook secret is not configured")
+	}
+	if signatureHeader == "" {
+		return fmt.Errorf("missing X-Gitea-Signature header")
+	}
+
+	expectedMac, err := hex.DecodeString(signatureHeader)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	actualMac := mac.Sum(nil)
+
+	if !hmac.Equal(actualMac, expectedMac) {
+		return fmt.Errorf("signature does not match payload")
+	}
+	return nil
+}
+
+func (p *GiteaProvider) GetPullRequest(event MergeRequestEvent, settings Settings) (PullRequest, error) {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/pulls/%d", settings.GiteaBaseURL, event.RepoFullName, event.Number)
+	res := giteaApiCall(url, "GET", "", settings)
+	if res.Error != nil {
+		return PullRequest{}, res.Error
+	}
+
+	var pr PullRequest
+	if err := json.Unmarshal(res.Body, &pr); err != nil {
+		return PullRequest{}, err
+	}
+	return pr, nil
+}
+
+// giteaMergeMethod maps a RepoPolicy.MergeMethod to the "Do" value Gitea's
+// merge endpoint expects, defaulting to a plain merge commit.
+func giteaMergeMethod(policyMergeMethod string) string {
+	switch policyMergeMethod {
+	case "squash", "rebase":
+		return policyMergeMethod
+	default:
+		return "merge"
+	}
+}
+
+func (p *GiteaProvider) Merge(event MergeRequestEvent, pr PullRequest, policy RepoPolicy, settings Settings) ApiResponse {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/pulls/%d/merge", settings.GiteaBaseURL, event.RepoFullName, event.Number)
+	payload := fmt.Sprintf(`{"Do": "%s"}`, giteaMergeMethod(policy.MergeMethod))
+	return giteaApiCall(url, "POST", payload, settings)
+}
+
+func (p *GiteaProvider) Comment(event MergeRequestEvent, body string, settings Settings) ApiResponse {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/issues/%d/comments", settings.GiteaBaseURL, event.RepoFullName, event.Number)
+
+	type commentBody struct {
+		Body string `json:"body"`
+	}
+	payload, err := json.Marshal(commentBody{Body: body})
+	if err != nil {
+		return ApiResponse{Error: err}
+	}
+	return giteaApiCall(url, "POST", string(payload), settings)
+}
+
+// giteaApiCall is apiCall's Gitea counterpart: same retry-on-5xx,
+// stop-on-4xx behavior (via doAuthenticatedRequest), but authenticated with
+// Gitea's own token, which uses the same "Authorization: token <token>"
+// scheme GitHub's installation tokens do.
+func giteaApiCall(url string, method string, payload string, settings Settings) ApiResponse {
+	req, err := http.NewRequest(method, url, strings.NewReader(payload))
+	if err != nil {
+		return ApiResponse{Body: nil, StatusCode: -1, Error: err}
+	}
+	req.Header.Add("Authorization", "token "+settings.GiteaToken)
+	req.Header.Add("content-type", "application/json")
+
+	return doAuthenticatedRequest(req)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+)
+
+func TestGiteaParseWebhook(t *testing.T) {
+	settings.GiteaWebhookSecret = "gitea-secret"
+
+	body := []byte(`{
+		"issue": {"number": 3, "state": "open", "pull_request": {"url": "https://gitea.example.com/pulls/3"}, "html_url": "https://gitea.example.com/issues/3"},
+		"comment": {"body": "please merge", "user": {"login": "jdoe"}},
+		"repository": {"full_name": "owner/repo"}
+	}`)
+
+	sign := func(body []byte) string {
+		mac := hmac.New(sha256.New, []byte(settings.GiteaWebhookSecret))
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	headers := func(event string, signature string) http.Header {
+		h := http.Header{}
+		h.Set("X-Gitea-Event", event)
+		h.Set("X-Gitea-Signature", signature)
+		return h
+	}
+
+	p := &GiteaProvider{}
+
+	t.Run("Parses an issue_comment event", func(t *testing.T) {
+		event, err := p.ParseWebhook(headers("issue_comment", sign(body)), body)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if event.RepoFullName != "owner/repo" || event.Number != 3 {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+		if !event.IsPullRequest {
+			t.Fatalf("expected IsPullRequest to be true")
+		}
+	})
+
+	t.Run("Rejects a mismatched signature", func(t *testing.T) {
+		if _, err := p.ParseWebhook(headers("issue_comment", "deadbeef"), body); err == nil {
+			t.Fatalf("expected an error for a mismatched signature")
+		}
+	})
+
+	t.Run("Ignores unsupported event types", func(t *testing.T) {
+		if _, err := p.ParseWebhook(headers("ping", sign(body)), body); err != ErrUnsupportedEvent {
+			t.Fatalf("expected ErrUnsupportedEvent, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GitHubProvider adapts GitHub's webhook format and REST API to the
+// Provider interface. handleRequest still drives GitHub merge requests
+// through autoMerge and MergeQueue directly off the IssueCommentWebhookEvent
+// ParseWebhook stashes on MergeRequestEvent.GitHubEvent, since only GitHub
+// exposes the mergeable/review/check-run APIs that pipeline depends on;
+// GetPullRequest/Merge/Comment below exist so GitHub can still be driven
+// through the plain Provider flow where that richer pipeline isn't needed.
+type GitHubProvider struct{}
+
+func (p *GitHubProvider) Name() string { return "github" }
+
+func (p *GitHubProvider) Matches(headers http.Header) bool {
+	return headers.Get("X-GitHub-Event") != "" || strings.HasPrefix(headers.Get("User-Agent"), "GitHub-Hookshot/")
+}
+
+func (p *GitHubProvider) ParseWebhook(headers http.Header, body []byte) (MergeRequestEvent, error) {
+	if err := verifyWebhookSignature(settings.WebhookSecret, body, headers.Get("X-Hub-Signature-256")); err != nil {
+		return MergeRequestEvent{}, fmt.Errorf("%w: %s", ErrInvalidSignature, err)
+	}
+
+	if headers.Get("X-GitHub-Event") != "issue_comment" {
+		return MergeRequestEvent{}, ErrUnsupportedEvent
+	}
+
+	if deliveryID := headers.Get("X-GitHub-Delivery"); deliveryID != "" && deliveries.SeenBefore(deliveryID) {
+		return MergeRequestEvent{}, ErrDuplicateDelivery
+	}
+
+	var raw IssueCommentWebhookEvent
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return MergeRequestEvent{}, err
+	}
+
+	return MergeRequestEvent{
+		RepoFullName:  raw.Repository.FullName,
+		Number:        raw.Issue.Number,
+		State:         raw.Issue.State,
+		HTMLURL:       raw.Issue.HTMLurl,
+		CommentBody:   raw.Comment.Body,
+		CommentUser:   raw.Comment.User.Login,
+		IsPullRequest: raw.Issue.EventPullRequest.URL != "",
+		DeliveryID:    headers.Get("X-GitHub-Delivery"),
+		GitHubEvent:   &raw,
+	}, nil
+}
+
+func (p *GitHubProvider) GetPullRequest(event MergeRequestEvent, settings Settings) (PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/pulls/%d", gitHubApiBaseUrl, event.RepoFullName, event.Number)
+	res := apiCall(url, "GET", "", settings)
+	if res.Error != nil {
+		return PullRequest{}, res.Error
+	}
+
+	var pr PullRequest
+	if err := json.Unmarshal(res.Body, &pr); err != nil {
+		return PullRequest{}, err
+	}
+	return pr, nil
+}
+
+// Merge mirrors autoMerge's merge call: same commit title/message
+// templating (when GitHubEvent is available to source commits/approvers
+// from) and the same json.Marshal'd payload, rather than a second,
+// divergent implementation of that logic.
+func (p *GitHubProvider) Merge(event MergeRequestEvent, pr PullRequest, policy RepoPolicy, settings Settings) ApiResponse {
+	url := fmt.Sprintf("%s/repos/%s/pulls/%d/merge", gitHubApiBaseUrl, event.RepoFullName, event.Number)
+
+	commitTitle, commitMessage := pr.Title, "PR automatically merged"
+	if event.GitHubEvent != nil {
+		pullChecker := NewPullChecker(apiCall)
+		approvers, err := pullChecker.Approvers(*event.GitHubEvent, settings)
+		if err != nil {
+			logger.Error("failed to fetch approvers", "repo", event.RepoFullName, "pr", event.Number, "error", err)
+		}
+		commits, err := fetchCommits(apiCall, *event.GitHubEvent, settings)
+		if err != nil {
+			logger.Error("failed to fetch commits", "repo", event.RepoFullName, "pr", event.Number, "error", err)
+		}
+		templateData := CommitTemplateData{PR: pr, Issue: event.GitHubEvent.Issue, Commits: commits, Approvers: approvers}
+
+		if rendered, err := renderTemplate(policy.CommitTitleTemplate, templateData); err != nil {
+			logger.Error("failed to render commit title template", "repo", event.RepoFullName, "error", err)
+		} else {
+			commitTitle = rendered
+		}
+		if rendered, err := renderTemplate(policy.CommitMessageTemplate, templateData); err != nil {
+			logger.Error("failed to render commit message template", "repo", event.RepoFullName, "error", err)
+		} else {
+			commitMessage = rendered
+		}
+	}
+
+	type mergeRequestBody struct {
+		CommitTitle   string `json:"commit_title"`
+		CommitMessage string `json:"commit_message"`
+		Sha           string `json:"sha"`
+		MergeMethod   string `json:"merge_method"`
+	}
+	payload, err := json.Marshal(mergeRequestBody{
+		CommitTitle:   commitTitle,
+		CommitMessage: commitMessage,
+		Sha:           pr.Head.Sha,
+		MergeMethod:   policy.MergeMethod,
+	})
+	if err != nil {
+		return ApiResponse{Error: err}
+	}
+	return apiCall(url, "PUT", string(payload), settings)
+}
+
+func (p *GitHubProvider) Comment(event MergeRequestEvent, body string, settings Settings) ApiResponse {
+	url := fmt.Sprintf("%s/repos/%s/issues/%d/comments", gitHubApiBaseUrl, event.RepoFullName, event.Number)
+
+	type commentBody struct {
+		Body string `json:"body"`
+	}
+	payload, err := json.Marshal(commentBody{Body: body})
+	if err != nil {
+		return ApiResponse{Error: err}
+	}
+	return apiCall(url, "POST", string(payload), settings)
+}
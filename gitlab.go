@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GitLabProvider adapts GitLab's "Note Hook" webhook events and merge
+// request API to the Provider interface. GitLab authenticates webhooks with
+// a static token rather than an HMAC signature, and authenticates API
+// requests with a PRIVATE-TOKEN header rather than GitHub's Authorization
+// scheme, so it makes its own HTTP requests instead of going through
+// apiCall/resolveAuthenticator.
+type GitLabProvider struct{}
+
+func (p *GitLabProvider) Name() string { return "gitlab" }
+
+func (p *GitLabProvider) Matches(headers http.Header) bool {
+	return headers.Get("X-Gitlab-Event") != "" || strings.HasPrefix(headers.Get("User-Agent"), "GitLab/")
+}
+
+type gitlabUser struct {
+	Username string `json:"username"`
+}
+
+type gitlabProject struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+type gitlabMergeRequest struct {
+	IID   int    `json:"iid"`
+	State string `json:"state"`
+	URL   string `json:"url"`
+}
+
+type gitlabObjectAttributes struct {
+	Note         string `json:"note"`
+	NoteableType string `json:"noteable_type"`
+	URL          string `json:"url"`
+}
+
+// gitlabNoteEvent is the payload GitLab sends for its "Note Hook" webhook,
+// fired whenever a comment is left on an issue, merge request, commit or
+// snippet. NoteableType distinguishes which of those this particular note
+// is on.
+type gitlabNoteEvent struct {
+	ObjectKind       string                 `json:"object_kind"`
+	Project          gitlabProject          `json:"project"`
+	User             gitlabUser             `json:"user"`
+	MergeRequest     gitlabMergeRequest     `json:"merge_request"`
+	ObjectAttributes gitlabObjectAttributes `json:"object_attributes"`
+}
+
+func (p *GitLabProvider) ParseWebhook(headers http.Header, body []byte) (MergeRequestEvent, error) {
+	token := headers.Get("X-Gitlab-Token")
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(settings.GitLabWebhookSecret)) != 1 {
+		return MergeRequestEvent{}, fmt.Errorf("%w: missing or mismatched X-Gitlab-Token header", ErrInvalidSignature)
+	}
+
+	if headers.Get("X-Gitlab-Event") != "Note Hook" {
+		return MergeRequestEvent{}, ErrUnsupportedEvent
+	}
+
+	if deliveryID := headers.Get("X-Gitlab-Event-UUID"); deliveryID != "" && deliveries.SeenBefore(deliveryID) {
+		return MergeRequestEvent{}, ErrDuplicateDelivery
+	}
+
+	var raw gitlabNoteEvent
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return MergeRequestEvent{}, err
+	}
+
+	if raw.ObjectAttributes.NoteableType != "MergeRequest" {
+		return MergeRequestEvent{}, ErrUnsupportedEvent
+	}
+
+	return MergeRequestEvent{
+		RepoID:        fmt.Sprintf("%d", raw.Project.ID),
+		RepoFullName:  raw.Project.PathWithNamespace,
+		Number:        raw.MergeRequest.IID,
+		State:         raw.MergeRequest.State,
+		HTMLURL:       raw.ObjectAttributes.URL,
+		CommentBody:   raw.ObjectAttributes.Note,
+		CommentUser:   raw.User.Username,
+		IsPullRequest: true,
+		DeliveryID:    headers.Get("X-Gitlab-Event-UUID"),
+	}, nil
+}
+
+type gitlabMergeRequestDetails struct {
+	State       string `json:"state"`
+	Draft       bool   `json:"draft"`
+	MergeStatus string `json:"merge_status"`
+	WebURL      string `json:"web_url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	SHA         string `json:"sha"`
+	Author      struct {
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+func (p *GitLabProvider) GetPullRequest(event MergeRequestEvent, settings Settings) (PullRequest, error) {
+	url := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d", settings.GitLabBaseURL, event.RepoID, event.Number)
+	res := gitlabApiCall(url, "GET", "", settings)
+	if res.Error != nil {
+		return PullRequest{}, res.Error
+	}
+
+	var mr gitlabMergeRequestDetails
+	if err := json.Unmarshal(res.Body, &mr); err != nil {
+		return PullRequest{}, err
+	}
+
+	return PullRequest{
+		URL:            mr.WebURL,
+		Head:           Head{Sha: mr.SHA},
+		Mergeable:      mr.MergeStatus == "can_be_merged",
+		MergeableState: mr.MergeStatus,
+		Draft:          mr.Draft,
+		Title:          mr.Title,
+		Body:           mr.Description,
+		User:           User{Login: mr.Author.Username},
+	}, nil
+}
+
+func (p *GitLabProvider) Merge(event MergeRequestEvent, pr PullRequest, policy RepoPolicy, settings Settings) ApiResponse {
+	url := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d/merge", settings.GitLabBaseURL, event.RepoID, event.Number)
+	payload := fmt.Sprintf(`{"squash": %t}`, policy.MergeMethod == "squash")
+	return gitlabApiCall(url, "PUT", payload, settings)
+}
+
+func (p *GitLabProvider) Comment(event MergeRequestEvent, body string, settings Settings) ApiResponse {
+	url := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d/notes", settings.GitLabBaseURL, event.RepoID, event.Number)
+
+	type noteBody struct {
+		Body string `json:"body"`
+	}
+	payload, err := json.Marshal(noteBody{Body: body})
+	if err != nil {
+		return ApiResponse{Error: err}
+	}
+	return gitlabApiCall(url, "POST", string(payload), settings)
+}
+
+// gitlabApiCall is apiCall's GitLab counterpart: same retry-on-5xx,
+// stop-on-4xx behavior (via doAuthenticatedRequest), but authenticated with
+// a PRIVATE-TOKEN header instead of apiCall's GitHub Authenticator.
+func gitlabApiCall(url string, method string, payload string, settings Settings) ApiResponse {
+	req, err := http.NewRequest(method, url, strings.NewReader(payload))
+	if err != nil {
+		return ApiResponse{Body: nil, StatusCode: -1, Error: err}
+	}
+	req.Header.Add("PRIVATE-TOKEN", settings.GitLabToken)
+	req.Header.Add("content-type", "application/json")
+
+	return doAuthenticatedRequest(req)
+}
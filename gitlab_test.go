@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGitLabParseWebhook(t *testing.T) {
+	settings.GitLabWebhookSecret = "gitlab-secret"
+
+	noteBody := []byte(`{
+		"object_kind": "note",
+		"project": {"id": 42, "path_with_namespace": "group/project"},
+		"user": {"username": "jdoe"},
+		"merge_request": {"iid": 7, "state": "opened", "url": "https://gitlab.example.com/group/project/-/merge_requests/7"},
+		"object_attributes": {"note": "please merge", "noteable_type": "MergeRequest", "url": "https://gitlab.example.com/group/project/-/merge_requests/7#note_1"}
+	}`)
+
+	headers := func(event string, token string) http.Header {
+		h := http.Header{}
+		h.Set("X-Gitlab-Event", event)
+		h.Set("X-Gitlab-Token", token)
+		return h
+	}
+
+	p := &GitLabProvider{}
+
+	t.Run("Parses a merge request note", func(t *testing.T) {
+		event, err := p.ParseWebhook(headers("Note Hook", "gitlab-secret"), noteBody)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if event.RepoID != "42" || event.RepoFullName != "group/project" || event.Number != 7 {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+		if event.CommentBody != "please merge" || event.CommentUser != "jdoe" {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+		if !event.IsPullRequest {
+			t.Fatalf("expected IsPullRequest to be true")
+		}
+	})
+
+	t.Run("Rejects a missing or mismatched token", func(t *testing.T) {
+		if _, err := p.ParseWebhook(headers("Note Hook", "wrong"), noteBody); err == nil {
+			t.Fatalf("expected an error for a mismatched token")
+		}
+	})
+
+	t.Run("Ignores unsupported event types", func(t *testing.T) {
+		if _, err := p.ParseWebhook(headers("Pipeline Hook", "gitlab-secret"), noteBody); err != ErrUnsupportedEvent {
+			t.Fatalf("expected ErrUnsupportedEvent, got %v", err)
+		}
+	})
+
+	t.Run("Ignores notes on issues rather than merge requests", func(t *testing.T) {
+		issueNoteBody := []byte(`{
+			"object_kind": "note",
+			"project": {"id": 42, "path_with_namespace": "group/project"},
+			"object_attributes": {"note": "please merge", "noteable_type": "Issue"}
+		}`)
+		if _, err := p.ParseWebhook(headers("Note Hook", "gitlab-secret"), issueNoteBody); err != ErrUnsupportedEvent {
+			t.Fatalf("expected ErrUnsupportedEvent, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+)
+
+// logger emits structured JSON so log lines can be shipped to and queried
+// in a real log aggregator, instead of grepping plain text.
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// A small, dependency-free Prometheus exposition-format registry. The repo
+// otherwise avoids pulling in heavier client libraries for a handful of
+// counters and a single histogram.
+
+type metric interface {
+	WriteTo(buf *bytes.Buffer)
+}
+
+func formatLabels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var parts []string
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		parts = append(parts, fmt.Sprintf(`%s=%q`, name, value))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+type labeledCounter struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newLabeledCounter(name string, help string, labelNames ...string) *labeledCounter {
+	return &labeledCounter{name: name, help: help, labelNames: labelNames, values: make(map[string]float64)}
+}
+
+func (c *labeledCounter) Inc(labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[strings.Join(labelValues, "\x1f")]++
+}
+
+func (c *labeledCounter) WriteTo(buf *bytes.Buffer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for key, value := range c.values {
+		var labelValues []string
+		if key != "" {
+			labelValues = strings.Split(key, "\x1f")
+		}
+		fmt.Fprintf(buf, "%s%s %g\n", c.name, formatLabels(c.labelNames, labelValues), value)
+	}
+}
+
+type gaugeMetric struct {
+	name string
+	help string
+
+	mu    sync.Mutex
+	value float64
+}
+
+func newGaugeMetric(name string, help string) *gaugeMetric {
+	return &gaugeMetric{name: name, help: help}
+}
+
+func (g *gaugeMetric) Set(value float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.value = value
+}
+
+func (g *gaugeMetric) WriteTo(buf *bytes.Buffer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", g.name, g.help, g.name, g.name, g.value)
+}
+
+type labeledGauge struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newLabeledGauge(name string, help string, labelNames ...string) *labeledGauge {
+	return &labeledGauge{name: name, help: help, labelNames: labelNames, values: make(map[string]float64)}
+}
+
+// Set replaces the full set of label combinations with the given values, so
+// combinations that are no longer present (e.g. a repo whose queue drained)
+// don't linger in the exposition output.
+func (g *labeledGauge) Set(values map[string]float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values = values
+}
+
+func (g *labeledGauge) WriteTo(buf *bytes.Buffer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	for key, value := range g.values {
+		var labelValues []string
+		if key != "" {
+			labelValues = strings.Split(key, "\x1f")
+		}
+		fmt.Fprintf(buf, "%s%s %g\n", g.name, formatLabels(g.labelNames, labelValues), value)
+	}
+}
+
+type histogramMetric struct {
+	name    string
+	help    string
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	total  uint64
+}
+
+func newHistogramMetric(name string, help string, buckets []float64) *histogramMetric {
+	return &histogramMetric{name: name, help: help, buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogramMetric) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sum += value
+	h.total++
+	for i, bucket := range h.buckets {
+		if value <= bucket {
+			h.counts[i]++
+		}
+	}
+}
+
+func (h *histogramMetric) WriteTo(buf *bytes.Buffer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for i, bucket := range h.buckets {
+		fmt.Fprintf(buf, `%s_bucket{le="%g"} %d`+"\n", h.name, bucket, h.counts[i])
+	}
+	fmt.Fprintf(buf, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.total)
+	fmt.Fprintf(buf, "%s_sum %g\n", h.name, h.sum)
+	fmt.Fprintf(buf, "%s_count %d\n", h.name, h.total)
+}
+
+var defaultDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+var (
+	webhookRequestsTotal        = newLabeledCounter("webhook_requests_total", "Total webhook requests received.", "event", "result")
+	mergesTotal                 = newLabeledCounter("merges_total", "Total merge attempts.", "repo", "result")
+	mergeDurationSeconds        = newHistogramMetric("merge_duration_seconds", "Time spent processing a single merge attempt.", defaultDurationBuckets)
+	githubApiRequestsTotal      = newLabeledCounter("github_api_requests_total", "Total GitHub API requests made.", "method", "status")
+	githubApiRateLimitRemaining = newGaugeMetric("github_api_rate_limit_remaining", "Remaining GitHub API rate limit, from the X-RateLimit-Remaining response header.")
+	retryAttemptsTotal          = newLabeledCounter("retry_attempts_total", "Total retry attempts made against the GitHub API.")
+	mergeQueueDepthByRepo       = newLabeledGauge("merge_queue_depth_by_repo", "Number of merge jobs currently queued, by repo.", "repo")
+)
+
+var registeredMetrics = []metric{
+	webhookRequestsTotal,
+	mergesTotal,
+	mergeDurationSeconds,
+	githubApiRequestsTotal,
+	githubApiRateLimitRemaining,
+	retryAttemptsTotal,
+}
+
+func renderMetrics() []byte {
+	var buf bytes.Buffer
+	for _, m := range registeredMetrics {
+		m.WriteTo(&buf)
+	}
+
+	depth := 0
+	depthByRepo := map[string]float64{}
+	if mergeQueue != nil {
+		depth = mergeQueue.Depth()
+		for repo, n := range mergeQueue.DepthByRepo() {
+			depthByRepo[repo] = float64(n)
+		}
+	}
+	fmt.Fprintf(&buf, "# HELP merge_queue_depth Number of merge jobs currently queued.\n# TYPE merge_queue_depth gauge\nmerge_queue_depth %d\n", depth)
+
+	mergeQueueDepthByRepo.Set(depthByRepo)
+	mergeQueueDepthByRepo.WriteTo(&buf)
+
+	return buf.Bytes()
+}
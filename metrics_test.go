@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLabeledCounter(t *testing.T) {
+	c := newLabeledCounter("test_counter_total", "a test counter", "event", "result")
+	c.Inc("issue_comment", "handled")
+	c.Inc("issue_comment", "handled")
+	c.Inc("issue_comment", "ignored")
+
+	var buf bytes.Buffer
+	c.WriteTo(&buf)
+
+	out := buf.String()
+	if !strings.Contains(out, `test_counter_total{event="issue_comment",result="handled"} 2`) {
+		t.Fatalf("expected handled count of 2, got %s", out)
+	}
+	if !strings.Contains(out, `test_counter_total{event="issue_comment",result="ignored"} 1`) {
+		t.Fatalf("expected ignored count of 1, got %s", out)
+	}
+}
+
+func TestHistogramMetric(t *testing.T) {
+	h := newHistogramMetric("test_duration_seconds", "a test histogram", []float64{1, 5})
+	h.Observe(0.5)
+	h.Observe(3)
+	h.Observe(10)
+
+	var buf bytes.Buffer
+	h.WriteTo(&buf)
+
+	out := buf.String()
+	if !strings.Contains(out, `test_duration_seconds_bucket{le="1"} 1`) {
+		t.Fatalf("expected one observation in the le=1 bucket, got %s", out)
+	}
+	if !strings.Contains(out, `test_duration_seconds_bucket{le="5"} 2`) {
+		t.Fatalf("expected two observations in the le=5 bucket, got %s", out)
+	}
+	if !strings.Contains(out, `test_duration_seconds_bucket{le="+Inf"} 3`) {
+		t.Fatalf("expected three total observations, got %s", out)
+	}
+	if !strings.Contains(out, "test_duration_seconds_count 3") {
+		t.Fatalf("expected a count of 3, got %s", out)
+	}
+}
+
+func TestGaugeMetric(t *testing.T) {
+	g := newGaugeMetric("test_gauge", "a test gauge")
+	g.Set(42)
+
+	var buf bytes.Buffer
+	g.WriteTo(&buf)
+
+	if !strings.Contains(buf.String(), "test_gauge 42") {
+		t.Fatalf("expected gauge value of 42, got %s", buf.String())
+	}
+}
+
+func TestRenderMetricsIncludesQueueDepth(t *testing.T) {
+	out := string(renderMetrics())
+	if !strings.Contains(out, "merge_queue_depth") {
+		t.Fatalf("expected merge_queue_depth to be rendered, got %s", out)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// MergeRequestEvent is the provider-agnostic shape handleRequest works with
+// once a Provider has parsed an incoming webhook delivery. Every provider
+// fills in the fields below; GitHubProvider additionally stashes the raw
+// GitHub payload in GitHubEvent, since autoMerge and MergeQueue still drive
+// GitHub's richer, GitHub-only pipeline (PullChecker, retries) directly off
+// it rather than off this generic struct.
+type MergeRequestEvent struct {
+	RepoID        string // provider-native repo/project identifier (e.g. GitLab's numeric project id); empty if RepoFullName is sufficient
+	RepoFullName  string // "owner/repo", used for policy lookups and logging
+	Number        int    // PR number (GitHub/Gitea) or merge request IID (GitLab)
+	State         string
+	HTMLURL       string
+	CommentBody   string
+	CommentUser   string
+	IsPullRequest bool
+	DeliveryID    string
+
+	GitHubEvent *IssueCommentWebhookEvent
+}
+
+// Provider adapts a single forge's webhook format and REST API to the
+// provider-agnostic flow in handleRequest.
+type Provider interface {
+	// Name identifies the provider for logging and metrics labels.
+	Name() string
+
+	// Matches reports whether this provider recognizes the incoming
+	// webhook delivery from its headers, before the body is parsed.
+	Matches(headers http.Header) bool
+
+	// ParseWebhook verifies the delivery (signature or token) and extracts
+	// a MergeRequestEvent from it. It returns ErrInvalidSignature,
+	// ErrUnsupportedEvent or ErrDuplicateDelivery for deliveries
+	// handleRequest should reject or ignore rather than act on.
+	ParseWebhook(headers http.Header, body []byte) (MergeRequestEvent, error)
+
+	// GetPullRequest fetches the current state of the pull/merge request
+	// the event refers to.
+	GetPullRequest(event MergeRequestEvent, settings Settings) (PullRequest, error)
+
+	// Merge attempts to merge the pull/merge request.
+	Merge(event MergeRequestEvent, pr PullRequest, policy RepoPolicy, settings Settings) ApiResponse
+
+	// Comment posts a comment back on the pull/merge request.
+	Comment(event MergeRequestEvent, body string, settings Settings) ApiResponse
+}
+
+// Sentinel errors ParseWebhook implementations return so handleRequest can
+// map them to the right HTTP response without caring which provider parsed
+// the delivery.
+var (
+	ErrInvalidSignature  = fmt.Errorf("webhook signature verification failed")
+	ErrUnsupportedEvent  = fmt.Errorf("unsupported event type")
+	ErrDuplicateDelivery = fmt.Errorf("duplicate delivery")
+)
+
+// providers is the set of enabled Providers, consulted in order by
+// matchProvider. GitHub is always enabled; main registers GitLab and/or
+// Gitea on top of it when their settings are configured.
+var providers = []Provider{&GitHubProvider{}}
+
+// matchProvider returns the first registered Provider that recognizes the
+// incoming webhook delivery, or nil if none do.
+func matchProvider(headers http.Header) Provider {
+	for _, p := range providers {
+		if p.Matches(headers) {
+			return p
+		}
+	}
+	return nil
+}
+
+// headerEventType extracts the provider-specific event type header for
+// logging and metrics, trying each known forge in turn.
+func headerEventType(headers http.Header) string {
+	for _, h := range []string{"X-GitHub-Event", "X-Gitlab-Event", "X-Gitea-Event"} {
+		if v := headers.Get(h); v != "" {
+			return v
+		}
+	}
+	return "unknown"
+}
+
+// doAuthenticatedRequest is the shared retry-on-5xx, stop-on-4xx body
+// gitlabApiCall and giteaApiCall run once req's auth header is set. It's
+// apiCall's logic minus GitHub's metrics/rate-limit instrumentation, which
+// only makes sense for GitHub's own request volume.
+func doAuthenticatedRequest(req *http.Request) ApiResponse {
+	return retry(3, time.Second, func() ApiResponse {
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return ApiResponse{Body: nil, StatusCode: -1, Error: err}
+		}
+		defer res.Body.Close()
+
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return ApiResponse{Body: body, StatusCode: res.StatusCode, Error: err}
+		}
+
+		s := res.StatusCode
+		switch {
+		case s >= 500:
+			return ApiResponse{Body: body, StatusCode: s, Error: fmt.Errorf("server error: %v", s)}
+		case s >= 400:
+			return ApiResponse{Body: body, StatusCode: s, Error: stop{fmt.Errorf("client error: %v", s)}}
+		default:
+			return ApiResponse{Body: body, StatusCode: s, Error: nil}
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMatchProvider(t *testing.T) {
+	original := providers
+	providers = []Provider{&GitHubProvider{}, &GitLabProvider{}, &GiteaProvider{}}
+	defer func() { providers = original }()
+
+	t.Run("Matches GitHub by event header", func(t *testing.T) {
+		headers := http.Header{}
+		headers.Set("X-GitHub-Event", "issue_comment")
+		if p := matchProvider(headers); p == nil || p.Name() != "github" {
+			t.Fatalf("expected github provider, got %v", p)
+		}
+	})
+
+	t.Run("Matches GitLab by event header", func(t *testing.T) {
+		headers := http.Header{}
+		headers.Set("X-Gitlab-Event", "Note Hook")
+		if p := matchProvider(headers); p == nil || p.Name() != "gitlab" {
+			t.Fatalf("expected gitlab provider, got %v", p)
+		}
+	})
+
+	t.Run("Matches Gitea by event header", func(t *testing.T) {
+		headers := http.Header{}
+		headers.Set("X-Gitea-Event", "issue_comment")
+		if p := matchProvider(headers); p == nil || p.Name() != "gitea" {
+			t.Fatalf("expected gitea provider, got %v", p)
+		}
+	})
+
+	t.Run("Returns nil when no provider recognizes the headers", func(t *testing.T) {
+		if p := matchProvider(http.Header{}); p != nil {
+			t.Fatalf("expected no provider to match, got %v", p)
+		}
+	})
+}
+
+func TestHeaderEventType(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("X-Gitea-Event", "issue_comment")
+	if got := headerEventType(headers); got != "issue_comment" {
+		t.Fatalf("expected issue_comment, got %s", got)
+	}
+
+	if got := headerEventType(http.Header{}); got != "unknown" {
+		t.Fatalf("expected unknown, got %s", got)
+	}
+}
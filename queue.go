@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxMergeAttempts = 6
+const maxMergeBackoff = 30 * time.Second
+
+// MergeJob is one queued merge attempt. It's persisted to disk so an
+// in-flight retry isn't lost if the process restarts.
+type MergeJob struct {
+	Event       IssueCommentWebhookEvent `json:"event"`
+	Policy      RepoPolicy               `json:"policy"`
+	Attempts    int                      `json:"attempts"`
+	NextAttempt time.Time                `json:"next_attempt"`
+}
+
+// MergeQueue retries merge attempts that fail for reasons expected to
+// resolve themselves shortly: GitHub hasn't finished computing mergeability
+// yet, or a background check is still running.
+type MergeQueue struct {
+	apiCall   ApiCall
+	jobs      chan *MergeJob
+	storePath string
+
+	mu      sync.Mutex
+	pending []*MergeJob
+
+	// writeMu serializes persist()'s writes to storePath, since Enqueue and
+	// each worker's requeue/process call persist() from independent
+	// goroutines; without it, two concurrent writes can interleave and
+	// corrupt the on-disk state.
+	writeMu sync.Mutex
+}
+
+func NewMergeQueue(storePath string, apiCall ApiCall, workers int) *MergeQueue {
+	q := &MergeQueue{
+		apiCall:   apiCall,
+		jobs:      make(chan *MergeJob, 1000),
+		storePath: storePath,
+	}
+
+	for _, job := range q.loadPersisted() {
+		q.track(job)
+		q.jobs <- job
+	}
+
+	for i := 0; i < workers; i++ {
+		go q.run()
+	}
+	return q
+}
+
+// Enqueue schedules a new merge attempt for immediate processing.
+func (q *MergeQueue) Enqueue(job *MergeJob) {
+	q.track(job)
+	q.persist()
+	q.jobs <- job
+}
+
+// Depth reports how many merge jobs are currently pending, for /metrics.
+func (q *MergeQueue) Depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// DepthByRepo reports how many merge jobs are currently pending per repo,
+// for /metrics' per-repo breakdown.
+func (q *MergeQueue) DepthByRepo() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	depths := make(map[string]int, len(q.pending))
+	for _, job := range q.pending {
+		depths[job.Event.Repository.FullName]++
+	}
+	return depths
+}
+
+func (q *MergeQueue) track(job *MergeJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, job)
+}
+
+func (q *MergeQueue) untrack(job *MergeJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, j := range q.pending {
+		if j == job {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			break
+		}
+	}
+}
+
+// persist snapshots q.pending to storePath. Writes are serialized by
+// writeMu and go through a temp file + rename so a crash or an interleaved
+// write from another goroutine can never leave storePath holding a partial
+// or corrupted snapshot.
+func (q *MergeQueue) persist() {
+	if q.storePath == "" {
+		return
+	}
+
+	q.mu.Lock()
+	body, err := json.Marshal(q.pending)
+	q.mu.Unlock()
+	if err != nil {
+		logger.Error("failed to marshal merge queue state", "error", err)
+		return
+	}
+
+	q.writeMu.Lock()
+	defer q.writeMu.Unlock()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(q.storePath), filepath.Base(q.storePath)+".tmp")
+	if err != nil {
+		logger.Error("failed to create temp file for merge queue state", "path", q.storePath, "error", err)
+		return
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		logger.Error("failed to write merge queue state", "path", q.storePath, "error", err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		logger.Error("failed to close merge queue state temp file", "path", q.storePath, "error", err)
+		return
+	}
+
+	if err := os.Rename(tmpPath, q.storePath); err != nil {
+		os.Remove(tmpPath)
+		logger.Error("failed to persist merge queue state", "path", q.storePath, "error", err)
+	}
+}
+
+func (q *MergeQueue) loadPersisted() []*MergeJob {
+	if q.storePath == "" {
+		return nil
+	}
+
+	body, err := ioutil.ReadFile(q.storePath)
+	if err != nil {
+		return nil
+	}
+
+	var jobs []*MergeJob
+	if err := json.Unmarshal(body, &jobs); err != nil {
+		logger.Error("failed to parse persisted merge queue state", "path", q.storePath, "error", err)
+		return nil
+	}
+	return jobs
+}
+
+func (q *MergeQueue) run() {
+	for job := range q.jobs {
+		if wait := time.Until(job.NextAttempt); wait > 0 {
+			time.Sleep(wait)
+		}
+		q.process(job)
+	}
+}
+
+func (q *MergeQueue) process(job *MergeJob) {
+	if !q.mergeabilityKnown(job) {
+		q.requeue(job, "mergeable state is still being computed")
+		return
+	}
+
+	comment := autoMerge(job.Event, q.apiCall, job.Policy)
+	if comment != "" && isTransientMergeFailure(comment) {
+		q.requeue(job, comment)
+		return
+	}
+
+	q.untrack(job)
+	q.persist()
+	if comment != "" {
+		q.postComment(job.Event, comment)
+	}
+}
+
+// mergeabilityKnown re-fetches the PR and reports false if GitHub hasn't
+// finished computing mergeable/mergeable_state yet, which happens for a
+// short window right after a push.
+func (q *MergeQueue) mergeabilityKnown(job *MergeJob) bool {
+	url := fmt.Sprintf("%s/repos/%s/pulls/%d", gitHubApiBaseUrl, job.Event.Repository.FullName, job.Event.Issue.Number)
+	res := q.apiCall(url, "GET", "", settings)
+	if res.Error != nil {
+		// don't stall the queue on a transport error; let autoMerge surface it
+		return true
+	}
+
+	var state struct {
+		Mergeable      *bool  `json:"mergeable"`
+		MergeableState string `json:"mergeable_state"`
+	}
+	if err := json.Unmarshal(res.Body, &state); err != nil {
+		return true
+	}
+
+	return state.Mergeable != nil && state.MergeableState != "unknown"
+}
+
+func (q *MergeQueue) requeue(job *MergeJob, reason string) {
+	job.Attempts++
+	if job.Attempts >= maxMergeAttempts {
+		q.untrack(job)
+		q.persist()
+		logger.Warn("giving up on merge job", "repo", job.Event.Repository.FullName, "pr", job.Event.Issue.Number, "attempts", job.Attempts, "reason", reason)
+		q.postComment(job.Event, fmt.Sprintf("Giving up after %d attempts: %s", job.Attempts, reason))
+		return
+	}
+
+	backoff := time.Second << uint(job.Attempts-1)
+	if backoff > maxMergeBackoff {
+		backoff = maxMergeBackoff
+	}
+	job.NextAttempt = time.Now().Add(backoff)
+	q.persist()
+
+	go func() {
+		time.Sleep(backoff)
+		q.jobs <- job
+	}()
+}
+
+func (q *MergeQueue) postComment(event IssueCommentWebhookEvent, comment string) {
+	logger.Info("commenting on pull request", "repo", event.Repository.FullName, "pr", event.Issue.Number, "comment", comment)
+	url := fmt.Sprintf("%s/repos/%s/issues/%d/comments", gitHubApiBaseUrl, event.Repository.FullName, event.Issue.Number)
+
+	type commentBody struct {
+		Body string `json:"body"`
+	}
+	payload, err := json.Marshal(commentBody{Body: comment})
+	if err != nil {
+		logger.Error("failed to marshal comment payload", "repo", event.Repository.FullName, "pr", event.Issue.Number, "error", err)
+		return
+	}
+
+	res := q.apiCall(url, "POST", string(payload), settings)
+	if res.Error != nil {
+		logger.Error("failed to comment on pull request", "repo", event.Repository.FullName, "pr", event.Issue.Number, "error", res.Error)
+	}
+}
+
+var transientMergeFailureMarkers = []string{
+	"try again later",
+	"head branch was modified",
+}
+
+// isTransientMergeFailure reports whether autoMerge's comment looks like the
+// kind of 405/409 GitHub returns while background checks are still running,
+// as opposed to a real policy failure.
+func isTransientMergeFailure(comment string) bool {
+	lower := strings.ToLower(comment)
+	for _, marker := range transientMergeFailureMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+var mergeQueue *MergeQueue
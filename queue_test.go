@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestIsTransientMergeFailure(t *testing.T) {
+	cases := []struct {
+		comment   string
+		transient bool
+	}{
+		{"Please try again later", true},
+		{"Head branch was modified. Review and try the merge again.", true},
+		{"Pull Request is not mergeable. Make sure there is approval and status checks have passed.", false},
+		{"", false},
+	}
+
+	for _, tc := range cases {
+		if got := isTransientMergeFailure(tc.comment); got != tc.transient {
+			t.Errorf("isTransientMergeFailure(%q) = %v, want %v", tc.comment, got, tc.transient)
+		}
+	}
+}
+
+func TestMergeabilityKnown(t *testing.T) {
+	job := &MergeJob{Event: IssueCommentWebhookEvent{Repository: Repository{FullName: "JohnRoesler/test"}, Issue: Issue{Number: 1}}}
+
+	t.Run("known when mergeable and state are set", func(t *testing.T) {
+		q := &MergeQueue{apiCall: func(url, method, payload string, s Settings) ApiResponse {
+			return ApiResponse{Body: []byte(`{"mergeable": true, "mergeable_state": "clean"}`), StatusCode: 200}
+		}}
+		if !q.mergeabilityKnown(job) {
+			t.Errorf("expected mergeability to be known")
+		}
+	})
+
+	t.Run("unknown when mergeable is null", func(t *testing.T) {
+		q := &MergeQueue{apiCall: func(url, method, payload string, s Settings) ApiResponse {
+			return ApiResponse{Body: []byte(`{"mergeable": null, "mergeable_state": "unknown"}`), StatusCode: 200}
+		}}
+		if q.mergeabilityKnown(job) {
+			t.Errorf("expected mergeability to be unknown")
+		}
+	})
+
+	t.Run("treated as known on transport error so autoMerge can surface it", func(t *testing.T) {
+		q := &MergeQueue{apiCall: func(url, method, payload string, s Settings) ApiResponse {
+			return ApiResponse{Error: errTest}
+		}}
+		if !q.mergeabilityKnown(job) {
+			t.Errorf("expected a transport error to be treated as known")
+		}
+	})
+}
+
+var errTest = &testError{"boom"}
+
+type testError struct{ msg string }
+
+func (e *testError) Error() string { return e.msg }
+
+func TestMergeQueueRequeueGivesUpAfterMaxAttempts(t *testing.T) {
+	var mu sync.Mutex
+	var posted []string
+
+	q := &MergeQueue{
+		apiCall: func(url, method, payload string, s Settings) ApiResponse {
+			if method == "POST" {
+				mu.Lock()
+				posted = append(posted, payload)
+				mu.Unlock()
+			}
+			return ApiResponse{Body: []byte(`{}`), StatusCode: 200}
+		},
+		jobs: make(chan *MergeJob, 10),
+	}
+
+	job := &MergeJob{Event: IssueCommentWebhookEvent{Repository: Repository{FullName: "JohnRoesler/test"}, Issue: Issue{Number: 1}}}
+	q.track(job)
+
+	for i := 0; i < maxMergeAttempts-1; i++ {
+		q.requeue(job, "still waiting")
+		if q.Depth() != 1 {
+			t.Fatalf("expected job to still be pending after attempt %d", i+1)
+		}
+	}
+
+	q.requeue(job, "still waiting")
+
+	if q.Depth() != 0 {
+		t.Fatalf("expected job to be dropped after %d attempts", maxMergeAttempts)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(posted) != 1 {
+		t.Fatalf("expected exactly one give-up comment, got %d", len(posted))
+	}
+	if !strings.Contains(posted[0], "Giving up") {
+		t.Errorf("expected give-up comment, got %s", posted[0])
+	}
+}
+
+func TestMergeQueueDepthAndPersistence(t *testing.T) {
+	q := NewMergeQueue("", func(url, method, payload string, s Settings) ApiResponse {
+		return ApiResponse{Body: []byte(`{}`), StatusCode: 200}
+	}, 0)
+
+	job := &MergeJob{Event: IssueCommentWebhookEvent{Repository: Repository{FullName: "JohnRoesler/test"}, Issue: Issue{Number: 1}}}
+	q.track(job)
+
+	if q.Depth() != 1 {
+		t.Fatalf("expected depth 1, got %d", q.Depth())
+	}
+
+	q.untrack(job)
+	if q.Depth() != 0 {
+		t.Fatalf("expected depth 0 after untrack, got %d", q.Depth())
+	}
+}
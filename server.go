@@ -2,23 +2,40 @@ package main
 
 import (
 	"crypto/tls"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"time"
 )
 
 type Settings struct {
-	GitHubUserName         string `yaml:"gitHubUserName"`
-	GitHubToken            string `yaml:"gitHubToken"`
-	RestrictMergeRequester string `yaml:"restrictMergeRequester"`
+	GitHubUserName       string `yaml:"gitHubUserName"`
+	GitHubToken          string `yaml:"gitHubToken"`
+	AuthMode             string `yaml:"authMode"`
+	GitHubAppID          string `yaml:"gitHubAppId"`
+	GitHubInstallationID string `yaml:"gitHubInstallationId"`
+	GitHubPrivateKeyPath string `yaml:"gitHubPrivateKeyPath"`
+	WebhookSecret        string `yaml:"webhookSecret"`
+	// RequiredApprovals is threaded into PullChecker's approvals check; the
+	// effective value comes from the repo's RepoPolicy, not this field
+	// directly, but PullChecker only knows how to read it off Settings.
+	RequiredApprovals string `yaml:"-"`
+
+	// GitLab and Gitea settings are only consulted by their respective
+	// Providers, registered in main only when their base URL is set.
+	GitLabBaseURL       string `yaml:"gitLabBaseUrl"`
+	GitLabToken         string `yaml:"gitLabToken"`
+	GitLabWebhookSecret string `yaml:"gitLabWebhookSecret"`
+	GiteaBaseURL        string `yaml:"giteaBaseUrl"`
+	GiteaToken          string `yaml:"giteaToken"`
+	GiteaWebhookSecret  string `yaml:"giteaWebhookSecret"`
 }
 
 var settings Settings
@@ -59,11 +76,14 @@ type Head struct {
 }
 
 type PullRequest struct {
-	URL       string `json:"url"`
-	Head      Head   `json:"head"`
-	Mergeable bool   `json:"mergeable"`
-	Title     string `json:"title"`
-	User      User   `json:"user"`
+	URL            string `json:"url"`
+	Head           Head   `json:"head"`
+	Mergeable      bool   `json:"mergeable"`
+	MergeableState string `json:"mergeable_state"`
+	Draft          bool   `json:"draft"`
+	Title          string `json:"title"`
+	Body           string `json:"body"`
+	User           User   `json:"user"`
 }
 
 type ApiResponse struct {
@@ -106,26 +126,41 @@ func apiCall(url string, method string, payload string, settings Settings) ApiRe
 		return ApiResponse{Body: nil, StatusCode: -1, Error: err}
 	}
 
-	basicAuthToken := base64.StdEncoding.EncodeToString([]byte(settings.GitHubUserName + ":" + settings.GitHubToken))
-
-	req.Header.Add("Authorization", "Basic "+basicAuthToken)
+	if err := resolveAuthenticator(settings).Authorize(req); err != nil {
+		return ApiResponse{Body: nil, StatusCode: -1, Error: err}
+	}
 	req.Header.Add("content-type", "application/json")
 
+	attempt := 0
 	return retry(3, time.Second, func() ApiResponse {
+		attempt++
+		if attempt > 1 {
+			retryAttemptsTotal.Inc()
+		}
+
 		res, err := http.DefaultClient.Do(req)
 		if err != nil {
+			githubApiRequestsTotal.Inc(method, "error")
 			return ApiResponse{Body: nil, StatusCode: -1, Error: err}
 		}
 
 		defer res.Body.Close()
 
+		if remaining := res.Header.Get("X-RateLimit-Remaining"); remaining != "" {
+			if n, err := strconv.ParseFloat(remaining, 64); err == nil {
+				githubApiRateLimitRemaining.Set(n)
+			}
+		}
+
 		body, err := ioutil.ReadAll(res.Body)
 		if err != nil {
+			githubApiRequestsTotal.Inc(method, strconv.Itoa(res.StatusCode))
 			// this results in a retry as we're passing back
 			return ApiResponse{Body: body, StatusCode: res.StatusCode, Error: err}
 		}
 
 		s := res.StatusCode
+		githubApiRequestsTotal.Inc(method, strconv.Itoa(s))
 		switch {
 		case s >= 500:
 			// Retry
@@ -142,7 +177,77 @@ func apiCall(url string, method string, payload string, settings Settings) ApiRe
 
 }
 
-func autoMerge(event IssueCommentWebhookEvent, apiCall ApiCall) string {
+// mergeabilityComment turns a PullChecker error into the user-visible
+// comment autoMerge has always posted back to the pull request.
+func mergeabilityComment(err error) string {
+	switch err {
+	case ErrNotOpen:
+		return "Pull request is not open."
+	case ErrNotMergeable, ErrNotClean:
+		return "Pull Request is not mergeable. Make sure there is approval and status checks have passed."
+	case ErrDraft:
+		return "Pull Request is marked as work in progress."
+	case ErrAwaitingReview:
+		return "Pull Request does not have enough approving reviews."
+	case ErrChecksFailing:
+		return "Required status checks have not passed."
+	case ErrChangesRequested:
+		return "Pull Request has changes requested that must be resolved first."
+	case ErrBlockedByDependency:
+		return "Pull Request depends on an issue that is still open."
+	default:
+		return "Pull Request is not mergeable. Make sure there is approval and status checks have passed."
+	}
+}
+
+// authorizeCommenter checks the trigger comment's author against the
+// repo's policy, shared by autoMerge's GitHub pipeline and mergeViaProvider.
+// AllowedCommenters is user-login-only (see the field doc on RepoPolicy);
+// it returns the user-visible rejection comment, or "" if the commenter is
+// authorized to request a merge.
+func authorizeCommenter(pr PullRequest, commentUser string, policy RepoPolicy) string {
+	// by default, the request to merge comment will only be honored if the opener of the PR makes the comment
+	// if merging is restricted to the requester, check comment user
+	restrictBool := true
+	if policy.RestrictMergeRequester != nil {
+		restrictBool = *policy.RestrictMergeRequester
+	}
+	if restrictBool && pr.User.Login != commentUser {
+		return "Merge request comment must be made by the pull request author."
+	}
+
+	if len(policy.AllowedCommenters) > 0 {
+		warnOnTeamCommenters(policy.AllowedCommenters)
+		if !containsLogin(policy.AllowedCommenters, commentUser) {
+			return "Merge request comment must come from an allowed user."
+		}
+	}
+
+	return ""
+}
+
+// warnOnTeamCommenters logs entries in allowed_commenters that look like a
+// "@org/team" reference rather than a plain login, since containsLogin
+// compares logins literally and team expansion is not implemented.
+func warnOnTeamCommenters(logins []string) {
+	for _, l := range logins {
+		if strings.Contains(l, "/") {
+			logger.Warn("allowed_commenters entry looks like a team, not a user login; team expansion is not supported and this entry will never match a commenter", "entry", l)
+		}
+	}
+}
+
+func autoMerge(event IssueCommentWebhookEvent, apiCall ApiCall, policy RepoPolicy) (comment string) {
+	start := time.Now()
+	defer func() {
+		mergeDurationSeconds.Observe(time.Since(start).Seconds())
+		result := "merged"
+		if comment != "" {
+			result = "rejected"
+		}
+		mergesTotal.Inc(event.Repository.FullName, result)
+	}()
+
 	if event.Issue.State != "open" {
 		return "Pull request is not open."
 	}
@@ -151,46 +256,73 @@ func autoMerge(event IssueCommentWebhookEvent, apiCall ApiCall) string {
 	urlPR := fmt.Sprintf("%s/repos/%s/pulls/%d", gitHubApiBaseUrl, event.Repository.FullName, event.Issue.Number)
 	prApiResponse := apiCall(urlPR, "GET", "", settings)
 	if prApiResponse.Error != nil {
-		log.Printf("Failed to get the pull request details: %s", prApiResponse.Error)
+		logger.Error("failed to get pull request details", "repo", event.Repository.FullName, "pr", event.Issue.Number, "error", prApiResponse.Error)
 		return "Error fetching pull request details. Try again."
 	}
 
 	var pr PullRequest
 	err := json.Unmarshal(prApiResponse.Body, &pr)
 	if err != nil {
-		log.Println(err)
+		logger.Error("failed to unmarshal pull request details", "repo", event.Repository.FullName, "pr", event.Issue.Number, "error", err)
 		return "Error fetching pull request details. Try again."
 	}
 
-	if !pr.Mergeable {
-		return "Pull Request is not mergeable. Make sure there is approval and status checks have passed."
+	checkSettings := settings
+	checkSettings.RequiredApprovals = strconv.Itoa(policy.RequiredApprovals)
+
+	pullChecker := NewPullChecker(apiCall)
+	if err := pullChecker.CheckMergeability(pr, event, checkSettings); err != nil {
+		return mergeabilityComment(err)
 	}
 
-	// by default, the request to merge comment will only be honored if the opener of the PR makes the comment
-	// if merging is restricted to the requester, check comment user
-	var restrictBool bool
-	if settings.RestrictMergeRequester != "" {
-		restrictBool, err = strconv.ParseBool(settings.RestrictMergeRequester)
-	} else {
-		// env not set, default to true
-		restrictBool = true
-	}
-	if restrictBool == true && pr.User.Login != event.Comment.User.Login {
-		return "Merge request comment must be made by the pull request author."
+	if rejection := authorizeCommenter(pr, event.Comment.User.Login, policy); rejection != "" {
+		return rejection
+	}
+
+	approvers, err := pullChecker.Approvers(event, checkSettings)
+	if err != nil {
+		logger.Error("failed to fetch approvers", "repo", event.Repository.FullName, "pr", event.Issue.Number, "error", err)
+	}
+	commits, err := fetchCommits(apiCall, event, settings)
+	if err != nil {
+		logger.Error("failed to fetch commits", "repo", event.Repository.FullName, "pr", event.Issue.Number, "error", err)
+	}
+	templateData := CommitTemplateData{PR: pr, Issue: event.Issue, Commits: commits, Approvers: approvers}
+
+	commitTitle, err := renderTemplate(policy.CommitTitleTemplate, templateData)
+	if err != nil {
+		logger.Error("failed to render commit title template", "repo", event.Repository.FullName, "error", err)
+		commitTitle = pr.Title
+	}
+	commitMessage, err := renderTemplate(policy.CommitMessageTemplate, templateData)
+	if err != nil {
+		logger.Error("failed to render commit message template", "repo", event.Repository.FullName, "error", err)
+		commitMessage = "PR automatically merged"
 	}
 
 	// try to merge the pr
 	urlMerge := fmt.Sprintf("%s/repos/%s/pulls/%d/merge", gitHubApiBaseUrl, event.Repository.FullName, event.Issue.Number)
-	payload := fmt.Sprintf(`{
-	"commit_title": "%s",
-	"commit_message": "PR automatically merged",
-	"sha": "%s",
-	"merge_method": "squash"
-	}`, pr.Title, pr.Head.Sha)
 
-	mergeApiResponse := apiCall(urlMerge, "PUT", payload, settings)
+	type mergeRequestBody struct {
+		CommitTitle   string `json:"commit_title"`
+		CommitMessage string `json:"commit_message"`
+		Sha           string `json:"sha"`
+		MergeMethod   string `json:"merge_method"`
+	}
+	payloadBytes, err := json.Marshal(mergeRequestBody{
+		CommitTitle:   commitTitle,
+		CommitMessage: commitMessage,
+		Sha:           pr.Head.Sha,
+		MergeMethod:   policy.MergeMethod,
+	})
+	if err != nil {
+		logger.Error("failed to marshal merge request payload", "repo", event.Repository.FullName, "pr", event.Issue.Number, "error", err)
+		return "Error building merge request payload."
+	}
+
+	mergeApiResponse := apiCall(urlMerge, "PUT", string(payloadBytes), settings)
 
-	log.Printf("Response: %d %s", mergeApiResponse.StatusCode, mergeApiResponse.Body)
+	logger.Info("merge api response", "repo", event.Repository.FullName, "pr", event.Issue.Number, "status", mergeApiResponse.StatusCode, "body", string(mergeApiResponse.Body))
 
 	type Body struct {
 		Message string `json:"message"`
@@ -200,7 +332,7 @@ func autoMerge(event IssueCommentWebhookEvent, apiCall ApiCall) string {
 
 	err = json.Unmarshal(mergeApiResponse.Body, &responseMessage)
 	if err != nil {
-		log.Println(err)
+		logger.Error("failed to unmarshal merge response", "repo", event.Repository.FullName, "pr", event.Issue.Number, "error", err)
 		return "Error fetching merge request response details."
 	}
 
@@ -208,12 +340,12 @@ func autoMerge(event IssueCommentWebhookEvent, apiCall ApiCall) string {
 
 	switch mergeApiResponse.StatusCode {
 	case 200:
-		log.Printf("Merged pull request: %s", pr.URL)
+		logger.Info("merged pull request", "url", pr.URL)
 		return ""
 	case 405, 409:
 		return message
 	default:
-		log.Printf("Unexpected response from pull request merge api, %d %s", mergeApiResponse.StatusCode, mergeApiResponse.Body)
+		logger.Error("unexpected response from pull request merge api", "status", mergeApiResponse.StatusCode, "body", string(mergeApiResponse.Body))
 		return message
 	}
 }
@@ -231,48 +363,140 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var event IssueCommentWebhookEvent
-	err = json.Unmarshal(b, &event)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Could not unmarshal body, %s", err.Error()), http.StatusInternalServerError)
+	provider := matchProvider(r.Header)
+	if provider == nil {
+		logger.Warn("rejecting webhook, no provider recognized the request")
+		webhookRequestsTotal.Inc("unknown", "no_provider")
+		http.Error(w, "Unrecognized webhook source", http.StatusBadRequest)
+		return
+	}
+
+	eventLabel := provider.Name() + ":" + headerEventType(r.Header)
+
+	event, err := provider.ParseWebhook(r.Header, b)
+	switch {
+	case errors.Is(err, ErrInvalidSignature):
+		logger.Warn("rejecting webhook, signature verification failed", "provider", provider.Name(), "error", err)
+		webhookRequestsTotal.Inc(eventLabel, "invalid_signature")
+		http.Error(w, "Invalid webhook signature", http.StatusUnauthorized)
+		return
+	case errors.Is(err, ErrUnsupportedEvent):
+		logger.Info("ignoring unsupported event type", "provider", provider.Name())
+		webhookRequestsTotal.Inc(eventLabel, "unsupported_event")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case errors.Is(err, ErrDuplicateDelivery):
+		logger.Info("ignoring duplicate delivery", "provider", provider.Name())
+		webhookRequestsTotal.Inc(eventLabel, "duplicate")
+		io.WriteString(w, "OK")
+		return
+	case err != nil:
+		webhookRequestsTotal.Inc(eventLabel, "unmarshal_error")
+		http.Error(w, fmt.Sprintf("Could not parse webhook, %s", err.Error()), http.StatusInternalServerError)
 		return
 	}
 
 	// no errors with request, so send a 200 and then do stuff
-	_, err = io.WriteString(w, "OK")
-	if err != nil {
+	if _, err := io.WriteString(w, "OK"); err != nil {
 		// log an error, but keep going, doesn't really matter if a response makes it back
-		log.Println(fmt.Errorf("Error sending response back to GitHub webhook, %s", err))
+		logger.Error("failed to send response back to webhook", "provider", provider.Name(), "error", err)
 	}
 
+	policy := PolicyFor(appConfig, event.RepoFullName)
+
 	// check if comment is what we're looking for, otherwise do nothing
-	if strings.ToLower(event.Comment.Body) != mergeComment {
-		log.Printf("Comment was not '%s', url: %s.", mergeComment, event.Comment.HTMLurl)
+	if strings.ToLower(event.CommentBody) != strings.ToLower(policy.TriggerComment) {
+		logger.Info("comment did not match trigger", "trigger", policy.TriggerComment, "url", event.HTMLURL)
+		webhookRequestsTotal.Inc(eventLabel, "ignored")
 		return
 	}
 
-	// if it's an issue and not a pull request, do nothing
-	if event.Issue.EventPullRequest.URL == "" {
-		log.Printf("Event triggered on issue and not pull request, url: %s.", event.Comment.HTMLurl)
+	// if it's an issue and not a pull/merge request, do nothing
+	if !event.IsPullRequest {
+		logger.Info("event triggered on issue and not pull request", "url", event.HTMLURL)
+		webhookRequestsTotal.Inc(eventLabel, "ignored")
 		return
 	}
 
-	comment := autoMerge(event, apiCall)
+	if event.GitHubEvent != nil {
+		if mergeQueue != nil {
+			mergeQueue.Enqueue(&MergeJob{Event: *event.GitHubEvent, Policy: policy})
+			webhookRequestsTotal.Inc(eventLabel, "queued")
+			return
+		}
+
+		comment := autoMerge(*event.GitHubEvent, apiCall, policy)
+		webhookRequestsTotal.Inc(eventLabel, "handled")
+		if comment != "" {
+			logger.Info("commenting on pull request", "pr", event.Number, "repo", event.RepoFullName, "comment", comment, "url", event.HTMLURL)
+			if res := provider.Comment(event, comment, settings); res.Error != nil {
+				logger.Error("failed to comment on pull request", "url", event.HTMLURL, "error", res.Error, "body", string(res.Body))
+			}
+		}
+		return
+	}
+
+	comment := mergeViaProvider(provider, event, policy)
+	webhookRequestsTotal.Inc(eventLabel, "handled")
 
 	if comment != "" {
-		// comment back on the pr
-		log.Printf("Commenting on PR #%d in: %s with comment: %s, url: %s", event.Issue.Number, event.Repository.FullName, comment, event.Issue.HTMLurl)
-		urlComment := fmt.Sprintf("%s/repos/%s/issues/%d/comments", gitHubApiBaseUrl, event.Repository.FullName, event.Issue.Number)
-		payload := fmt.Sprintf(`{
-		"body": "%s"
-		}`, comment)
-		commentApiResponse := apiCall(urlComment, "POST", payload, settings)
-		if commentApiResponse.Error != nil {
-			log.Printf("Failed to comment on the pull request: %s with failure reason: %s %s", event.Issue.HTMLurl, commentApiResponse.Error, commentApiResponse.Body)
+		logger.Info("commenting on pull request", "pr", event.Number, "repo", event.RepoFullName, "comment", comment, "url", event.HTMLURL)
+		if res := provider.Comment(event, comment, settings); res.Error != nil {
+			logger.Error("failed to comment on pull request", "url", event.HTMLURL, "error", res.Error, "body", string(res.Body))
 		}
 	}
 }
 
+// mergeViaProvider is the non-GitHub counterpart to autoMerge: a basic
+// mergeability check plus merge attempt against providers whose APIs don't
+// expose GitHub's review/check-run/mergeable-state machinery for
+// PullChecker to drive. It has no PullChecker behind it, so it only checks
+// Draft, Mergeable and commenter authorization; it returns the comment to
+// post back, or "" on a successful merge. In particular,
+// policy.RequiredApprovals is NOT enforced here, so a repo policy that
+// requires N approvals is silently ignored for GitLab/Gitea repos.
+func mergeViaProvider(provider Provider, event MergeRequestEvent, policy RepoPolicy) (comment string) {
+	start := time.Now()
+	defer func() {
+		mergeDurationSeconds.Observe(time.Since(start).Seconds())
+		result := "merged"
+		if comment != "" {
+			result = "rejected"
+		}
+		mergesTotal.Inc(event.RepoFullName, result)
+	}()
+
+	pr, err := provider.GetPullRequest(event, settings)
+	if err != nil {
+		logger.Error("failed to get pull request details", "provider", provider.Name(), "repo", event.RepoFullName, "pr", event.Number, "error", err)
+		return "Error fetching pull request details. Try again."
+	}
+
+	if pr.Draft {
+		return "Pull Request is marked as work in progress."
+	}
+	if !pr.Mergeable {
+		return "Pull Request is not mergeable. Make sure there is approval and status checks have passed."
+	}
+
+	if policy.RequiredApprovals > 0 {
+		logger.Warn("required_approvals is not enforced for this provider", "provider", provider.Name(), "repo", event.RepoFullName, "pr", event.Number, "required_approvals", policy.RequiredApprovals)
+	}
+
+	if rejection := authorizeCommenter(pr, event.CommentUser, policy); rejection != "" {
+		return rejection
+	}
+
+	mergeApiResponse := provider.Merge(event, pr, policy, settings)
+	if mergeApiResponse.Error != nil {
+		logger.Error("failed to merge pull request", "provider", provider.Name(), "repo", event.RepoFullName, "pr", event.Number, "error", mergeApiResponse.Error, "body", string(mergeApiResponse.Body))
+		return "Pull Request is not mergeable. Make sure there is approval and status checks have passed."
+	}
+
+	logger.Info("merged pull request", "provider", provider.Name(), "url", pr.URL)
+	return ""
+}
+
 func health(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, fmt.Sprintf("Method not allowed"), http.StatusMethodNotAllowed)
@@ -281,30 +505,116 @@ func health(w http.ResponseWriter, r *http.Request) {
 
 	_, err := io.WriteString(w, "OK")
 	if err != nil {
-		log.Println(fmt.Errorf("Error sending response to health check, %s", err))
+		logger.Error("failed to send response to health check", "error", err)
 		return
 	}
-	log.Println("Request made to /health")
+	logger.Info("request made to /health")
+}
+
+func metrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, fmt.Sprintf("Method not allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Write(renderMetrics())
+}
+
+// version reports the module's build info, so it's possible to tell which
+// revision a running instance was built from without cross-referencing logs.
+func version(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, fmt.Sprintf("Method not allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		http.Error(w, "Build info not available", http.StatusInternalServerError)
+		return
+	}
+
+	revision := "unknown"
+	modified := false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+
+	w.Header().Set("content-type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		GoVersion string `json:"goVersion"`
+		Revision  string `json:"revision"`
+		Modified  bool   `json:"modified"`
+	}{GoVersion: info.GoVersion, Revision: revision, Modified: modified})
 }
 
 func main() {
 	// don't verify when calling to GitHub, otherwise we need a cert bundle
 	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 
-	log.Println("Server starting...")
+	logger.Info("server starting...")
 
 	settings.GitHubUserName = os.Getenv("GITHUB_USERNAME")
 	settings.GitHubToken = os.Getenv("GITHUB_TOKEN")
-	settings.RestrictMergeRequester = os.Getenv("RESTRICT_MERGE_REQUESTER")
+	settings.AuthMode = os.Getenv("AUTH_MODE")
+	settings.GitHubAppID = os.Getenv("GITHUB_APP_ID")
+	settings.GitHubInstallationID = os.Getenv("GITHUB_INSTALLATION_ID")
+	settings.GitHubPrivateKeyPath = os.Getenv("GITHUB_PRIVATE_KEY_PATH")
+	settings.WebhookSecret = os.Getenv("WEBHOOK_SECRET")
+
+	settings.GitLabBaseURL = os.Getenv("GITLAB_BASE_URL")
+	settings.GitLabToken = os.Getenv("GITLAB_TOKEN")
+	settings.GitLabWebhookSecret = os.Getenv("GITLAB_WEBHOOK_SECRET")
+	settings.GiteaBaseURL = os.Getenv("GITEA_BASE_URL")
+	settings.GiteaToken = os.Getenv("GITEA_TOKEN")
+	settings.GiteaWebhookSecret = os.Getenv("GITEA_WEBHOOK_SECRET")
+
+	if settings.WebhookSecret == "" {
+		logger.Error("WEBHOOK_SECRET not set, cannot verify incoming webhooks.")
+		os.Exit(1)
+	}
+
+	// GitHub is always enabled; GitLab and Gitea are opt-in, enabled by
+	// setting their base URL.
+	if settings.GitLabBaseURL != "" {
+		providers = append(providers, &GitLabProvider{})
+	}
+	if settings.GiteaBaseURL != "" {
+		providers = append(providers, &GiteaProvider{})
+	}
 
-	if settings.GitHubToken == "" || settings.GitHubUserName == "" {
-		log.Fatalf("GitHub username or token not set, cannot start application.")
+	if configFile := os.Getenv("CONFIG_FILE"); configFile != "" {
+		cfg, err := LoadConfig(configFile)
+		if err != nil {
+			logger.Error("could not load config file", "path", configFile, "error", err)
+			os.Exit(1)
+		}
+		appConfig = cfg
 	}
 
+	if settings.AuthMode == "app" {
+		if settings.GitHubAppID == "" || settings.GitHubInstallationID == "" || settings.GitHubPrivateKeyPath == "" {
+			logger.Error("AUTH_MODE=app requires GITHUB_APP_ID, GITHUB_INSTALLATION_ID and GITHUB_PRIVATE_KEY_PATH to be set.")
+			os.Exit(1)
+		}
+	} else if settings.GitHubToken == "" || settings.GitHubUserName == "" {
+		logger.Error("GitHub username or token not set, cannot start application.")
+		os.Exit(1)
+	}
+
+	mergeQueue = NewMergeQueue(os.Getenv("MERGE_QUEUE_STORE"), apiCall, 2)
+
 	port := "8080"
 
 	http.HandleFunc("/", handleRequest)
 	http.HandleFunc("/health", health)
-	log.Printf("Server started, listening on port %s", port)
-	log.Print(http.ListenAndServe(":"+port, nil))
+	http.HandleFunc("/metrics", metrics)
+	http.HandleFunc("/version", version)
+	logger.Info("server started", "port", port)
+	logger.Error("server exited", "error", http.ListenAndServe(":"+port, nil))
 }
@@ -2,10 +2,14 @@ package main
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -14,11 +18,30 @@ var apiResponsePRDetails ApiResponse
 var apiResponseMerge ApiResponse
 
 func mockApiCall(url string, method string, payload string, s Settings) ApiResponse {
-	if method == "GET" {
-		return apiResponsePRDetails
-	} else {
+	if method != "GET" {
 		return apiResponseMerge
 	}
+
+	switch {
+	case strings.HasSuffix(url, "/reviews"):
+		return ApiResponse{
+			Body:       []byte(`[{"user":{"login":"SomeReviewer"},"state":"APPROVED"}]`),
+			StatusCode: 200,
+		}
+	case strings.HasSuffix(url, "/comments"):
+		return ApiResponse{Body: []byte(`[]`), StatusCode: 200}
+	case strings.HasSuffix(url, "/check-runs"):
+		return ApiResponse{
+			Body:       []byte(`{"check_runs":[{"status":"completed","conclusion":"success"}]}`),
+			StatusCode: 200,
+		}
+	case strings.HasSuffix(url, "/status"):
+		return ApiResponse{Body: []byte(`{"state":"success"}`), StatusCode: 200}
+	case strings.Contains(url, "/issues/"):
+		return ApiResponse{Body: []byte(`{"state":"closed"}`), StatusCode: 200}
+	default:
+		return apiResponsePRDetails
+	}
 }
 
 func TestAutoMerge(t *testing.T) {
@@ -173,11 +196,13 @@ func TestAutoMerge(t *testing.T) {
 		apiResponsePRDetails = tc.apiResponsePRDetails
 		apiResponseMerge = tc.apiResponseMerge
 		t.Run(tc.name, func(t *testing.T) {
+			policy := builtInDefaultPolicy()
 			if tc.name == "Allow merge by non author if restrict merge is false" {
-				settings.RestrictMergeRequester = "false"
+				allowAll := false
+				policy.RestrictMergeRequester = &allowAll
 			}
 
-			comment := autoMerge(tc.event, mockApiCall)
+			comment := autoMerge(tc.event, mockApiCall, policy)
 			if comment != tc.expectedComment {
 				t.Fatalf("Expected comment to be: %s, found: %s", tc.expectedComment, comment)
 			}
@@ -207,11 +232,26 @@ func TestHandleRequest(t *testing.T) {
 		t.Fatalf("failed to marshall json")
 	}
 
-	t.Run("Should return 200 on POST with valid body", func(t *testing.T) {
-		req, err := http.NewRequest("POST", "localhost:8080", bytes.NewBuffer(jsonBody))
+	settings.WebhookSecret = "test-secret"
+	signBody := func(body []byte) string {
+		mac := hmac.New(sha256.New, []byte(settings.WebhookSecret))
+		mac.Write(body)
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	signedRequest := func(t *testing.T, method string, body []byte, deliveryID string) *http.Request {
+		req, err := http.NewRequest(method, "localhost:8080", bytes.NewBuffer(body))
 		if err != nil {
 			t.Fatalf("could not create request: %v", err)
 		}
+		req.Header.Set("X-Hub-Signature-256", signBody(body))
+		req.Header.Set("X-GitHub-Event", "issue_comment")
+		req.Header.Set("X-GitHub-Delivery", deliveryID)
+		return req
+	}
+
+	t.Run("Should return 200 on POST with valid body", func(t *testing.T) {
+		req := signedRequest(t, "POST", jsonBody, "delivery-1")
 
 		rec := httptest.NewRecorder()
 
@@ -223,6 +263,37 @@ func TestHandleRequest(t *testing.T) {
 		}
 	})
 
+	t.Run("Should return 401 on missing or invalid signature", func(t *testing.T) {
+		req, err := http.NewRequest("POST", "localhost:8080", bytes.NewBuffer(jsonBody))
+		if err != nil {
+			t.Fatalf("could not create request: %v", err)
+		}
+		req.Header.Set("X-GitHub-Event", "issue_comment")
+
+		rec := httptest.NewRecorder()
+
+		handleRequest(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected status 401 Unauthorized, got %s", res.Status)
+		}
+	})
+
+	t.Run("Should return 204 on unsupported event type", func(t *testing.T) {
+		req := signedRequest(t, "POST", jsonBody, "delivery-2")
+		req.Header.Set("X-GitHub-Event", "ping")
+
+		rec := httptest.NewRecorder()
+
+		handleRequest(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != http.StatusNoContent {
+			t.Errorf("expected status 204 No Content, got %s", res.Status)
+		}
+	})
+
 	t.Run("Should return 405 on non-POST method", func(t *testing.T) {
 
 		for _, method := range []string{"GET", "PUT", "HEAD", "TRACE"} {
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const signaturePrefix = "sha256="
+
+// verifyWebhookSignature checks the X-Hub-Signature-256 header GitHub signs
+// every webhook delivery with, so handleRequest can reject anything that
+// didn't actually come from GitHub.
+func verifyWebhookSignature(secret string, body []byte, signatureHeader string) error {
+	if secret == "" {
+		return fmt.Errorf("webhook secret is not configured")
+	}
+
+	if !strings.HasPrefix(signatureHeader, signaturePrefix) {
+		return fmt.Errorf("missing or malformed X-Hub-Signature-256 header")
+	}
+
+	expectedMac, err := hex.DecodeString(strings.TrimPrefix(signatureHeader, signaturePrefix))
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	actualMac := mac.Sum(nil)
+
+	if !hmac.Equal(actualMac, expectedMac) {
+		return fmt.Errorf("signature does not match payload")
+	}
+	return nil
+}
+
+// deliveryDedupe remembers the last few X-GitHub-Delivery ids so a retried
+// webhook delivery (GitHub retries on timeout or 5xx) doesn't trigger a
+// second merge attempt.
+type deliveryDedupe struct {
+	mu       sync.Mutex
+	capacity int
+	order    []string
+	seen     map[string]struct{}
+}
+
+func newDeliveryDedupe(capacity int) *deliveryDedupe {
+	return &deliveryDedupe{
+		capacity: capacity,
+		seen:     make(map[string]struct{}),
+	}
+}
+
+// SeenBefore reports whether id has already been recorded, and records it
+// if not. The oldest id is evicted once capacity is exceeded.
+func (d *deliveryDedupe) SeenBefore(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.seen[id]; ok {
+		return true
+	}
+
+	d.seen[id] = struct{}{}
+	d.order = append(d.order, id)
+	if len(d.order) > d.capacity {
+		oldest := d.order[0]
+		d.order = d.order[1:]
+		delete(d.seen, oldest)
+	}
+	return false
+}
+
+var deliveries = newDeliveryDedupe(500)
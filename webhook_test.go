@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	secret := "shhh"
+	body := []byte(`{"hello":"world"}`)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	validSignature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	t.Run("Accepts a valid signature", func(t *testing.T) {
+		if err := verifyWebhookSignature(secret, body, validSignature); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Rejects a missing signature", func(t *testing.T) {
+		if err := verifyWebhookSignature(secret, body, ""); err == nil {
+			t.Fatalf("expected an error for a missing signature")
+		}
+	})
+
+	t.Run("Rejects a mismatched signature", func(t *testing.T) {
+		if err := verifyWebhookSignature(secret, body, "sha256=deadbeef"); err == nil {
+			t.Fatalf("expected an error for a mismatched signature")
+		}
+	})
+
+	t.Run("Rejects when no secret is configured", func(t *testing.T) {
+		if err := verifyWebhookSignature("", body, validSignature); err == nil {
+			t.Fatalf("expected an error when no secret is configured")
+		}
+	})
+}
+
+func TestDeliveryDedupe(t *testing.T) {
+	t.Run("Reports duplicate deliveries", func(t *testing.T) {
+		d := newDeliveryDedupe(10)
+		if d.SeenBefore("abc") {
+			t.Fatalf("expected first sighting to not be a duplicate")
+		}
+		if !d.SeenBefore("abc") {
+			t.Fatalf("expected second sighting to be a duplicate")
+		}
+	})
+
+	t.Run("Evicts the oldest id once capacity is exceeded", func(t *testing.T) {
+		d := newDeliveryDedupe(2)
+		d.SeenBefore("one")
+		d.SeenBefore("two")
+		d.SeenBefore("three")
+
+		if d.SeenBefore("one") {
+			t.Fatalf("expected evicted id to no longer be a duplicate")
+		}
+	})
+}